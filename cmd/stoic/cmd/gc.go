@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcKeep int
+)
+
+func init() {
+	gcCmd.Flags().IntVar(
+		&gcKeep, "keep", 2,
+		"number of checkouts to keep per tool, besides current and pinned ones")
+	rootCmd.AddCommand(gcCmd)
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove old checkouts and unused environments",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return gc(gcKeep)
+	},
+}
+
+func gc(keep int) error {
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+
+	reclaimed, err := engine.CollectGarbage(keep)
+	fmt.Printf("Reclaimed %v\n", formatBytes(reclaimed))
+	return err
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
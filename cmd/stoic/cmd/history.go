@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history tool",
+	Short: "List past checkouts of a tool",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return history(args[0])
+	},
+}
+
+func history(toolName string) error {
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+
+	records, err := engine.ToolHistory(toolName)
+	if err != nil {
+		return err
+	}
+
+	held := tool.NullVersion
+	for _, t := range engine.Tools() {
+		if t.Name() == toolName {
+			held = t.HeldVersion()
+		}
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tCREATED\tLAST CURRENT\tSTATUS\tPATH")
+	for _, r := range records {
+		lastCurrent := "never"
+		if !r.LastSetCurrent.IsZero() {
+			lastCurrent = r.LastSetCurrent.Format(time.RFC3339)
+		}
+
+		status := "-"
+		switch {
+		case r.IsCurrent && r.Version() == held:
+			status = "current, held"
+		case r.IsCurrent:
+			status = "current"
+		default:
+			if _, err := os.Stat(r.Path()); err != nil {
+				status = "missing"
+			}
+		}
+
+		fmt.Fprintf(out, "%v\t%v\t%v\t%v\t%v\n",
+			r.Version(), r.Created.Format(time.RFC3339), lastCurrent, status, r.Path())
+	}
+	out.Flush()
+	return nil
+}
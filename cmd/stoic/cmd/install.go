@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	installJobs int
+)
+
+func init() {
+	installCmd.Flags().IntVarP(
+		&installJobs, "jobs", "j", 4, "number of tools to install concurrently")
+	rootCmd.AddCommand(installCmd)
+}
+
+var installCmd = &cobra.Command{
+	Use:     "install [tool...]",
+	Aliases: []string{"prefetch"},
+	Short:   "Fetch and set up tools ahead of running them",
+	Long: "Fetch and set up tools ahead of running them.\n\n" +
+		"All configured tools are installed, unless specific tools are named.",
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return install(args, installJobs)
+	},
+}
+
+func install(toolNames []string, jobs int) error {
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+	return engine.Prepare(toolNames, jobs)
+}
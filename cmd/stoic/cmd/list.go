@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stoic-cli/stoic-cli-core"
+)
+
+var (
+	listOutput string
+)
+
+func init() {
+	listCmd.Flags().StringVarP(
+		&listOutput, "output", "o", "table", "output format, table or json")
+	rootCmd.AddCommand(listCmd)
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured tools and their status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return list(listOutput)
+	},
+}
+
+type toolStatus struct {
+	Name            string     `json:"name"`
+	Endpoint        string     `json:"endpoint"`
+	Channel         string     `json:"channel,omitempty"`
+	Getter          string     `json:"getter"`
+	Runner          string     `json:"runner"`
+	PinVersion      string     `json:"pin-version,omitempty"`
+	Version         string     `json:"version,omitempty"`
+	Satisfied       *bool      `json:"version-satisfied,omitempty"`
+	UpstreamVersion string     `json:"upstream-version,omitempty"`
+	CurrentVersion  string     `json:"current-version,omitempty"`
+	HeldVersion     string     `json:"held-version,omitempty"`
+	UpdateFrequency string     `json:"update,omitempty"`
+	LastUpdate      *time.Time `json:"last-update,omitempty"`
+	UpdateDue       bool       `json:"update-due"`
+}
+
+func newToolStatus(t stoic.Tool) toolStatus {
+	status := toolStatus{
+		Name:            t.Name(),
+		Endpoint:        strings.TrimPrefix(t.Endpoint().String(), "https://"),
+		Channel:         string(t.Channel()),
+		Getter:          t.Config().Getter.Type,
+		Runner:          t.Config().Runner.Type,
+		PinVersion:      string(t.Config().PinVersion),
+		UpstreamVersion: string(t.UpstreamVersion()),
+		HeldVersion:     string(t.HeldVersion()),
+		UpdateFrequency: t.UpdateFrequency().String(),
+		UpdateDue:       t.IsUpdateDue(),
+	}
+
+	if checkout := t.CurrentCheckout(); checkout != nil {
+		status.CurrentVersion = string(checkout.Version())
+	}
+	if constraint := t.Config().Version; constraint.IsSet() {
+		satisfied := t.SatisfiesVersionConstraint()
+		status.Version = string(constraint)
+		status.Satisfied = &satisfied
+	}
+	if lastUpdate := t.LastUpdate(); !lastUpdate.IsZero() {
+		status.LastUpdate = &lastUpdate
+	}
+
+	return status
+}
+
+func list(output string) error {
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+
+	tools := engine.Tools()
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name() < tools[j].Name()
+	})
+
+	statuses := make([]toolStatus, 0, len(tools))
+	for _, t := range tools {
+		statuses = append(statuses, newToolStatus(t))
+	}
+
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+
+	case "table":
+		printToolStatuses(os.Stdout, statuses)
+		return nil
+
+	default:
+		return errors.Errorf("unknown output format, '%v'", output)
+	}
+}
+
+func printToolStatuses(w io.Writer, statuses []toolStatus) {
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "NAME\tENDPOINT\tCHANNEL\tGETTER\tRUNNER\t"+
+		"PINNED\tVERSION\tUPSTREAM\tCURRENT\tLAST UPDATE\tUPDATE DUE")
+	for _, s := range statuses {
+		lastUpdate := "never"
+		if s.LastUpdate != nil {
+			lastUpdate = s.LastUpdate.Format(time.RFC3339)
+		}
+
+		current := orNone(s.CurrentVersion)
+		if s.HeldVersion != "" {
+			current += " (held)"
+		}
+
+		version := orNone(s.Version)
+		if s.Satisfied != nil {
+			if *s.Satisfied {
+				version += " (satisfied)"
+			} else {
+				version += " (not satisfied)"
+			}
+		}
+
+		updateDue := "no"
+		if s.UpdateDue {
+			updateDue = "yes"
+		}
+
+		fmt.Fprintf(out, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			s.Name, s.Endpoint, orNone(s.Channel), s.Getter, s.Runner,
+			orNone(s.PinVersion), version, orNone(s.UpstreamVersion),
+			current, lastUpdate, updateDue)
+	}
+	out.Flush()
+}
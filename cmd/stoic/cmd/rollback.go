@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackHold bool
+)
+
+func init() {
+	rollbackCmd.Flags().BoolVar(
+		&rollbackHold, "hold", true,
+		"keep the previous version until the upstream version changes")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback tool",
+	Short: "Roll back a tool to its previously current checkout",
+	Long: `Roll back a tool to its previously current checkout.
+
+By default, the previous version is held in place of the current upstream
+version until a new upstream version is available. With --hold=false, the tool
+is updated again the next time an update is due.
+
+Rolling back again returns to the checkout that was rolled back from.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rollback(args[0], rollbackHold)
+	},
+}
+
+func rollback(toolName string, hold bool) error {
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+
+	from, to, err := engine.RollbackTool(toolName, hold)
+	if err != nil {
+		return err
+	}
+
+	suffix := ""
+	if hold {
+		suffix = " (held until the upstream version changes)"
+	}
+	fmt.Printf("%v: %v -> %v%v\n", toolName, from, to, suffix)
+	return nil
+}
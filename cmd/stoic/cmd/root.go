@@ -3,9 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/engine"
+	"github.com/stoic-cli/stoic-cli-core/util"
 )
 
 var rootCmd = &cobra.Command{
@@ -18,9 +21,18 @@ var rootCmd = &cobra.Command{
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		if _, ok := err.(*exec.ExitError); !ok {
-			fmt.Fprintf(os.Stderr, "Error: %s", err)
+		// Mirror the exit status of tools run as a child process
+		if exitErr, ok := err.(*util.ExitStatusError); ok {
+			util.ExitWithStatus(exitErr.ProcessState)
 		}
+
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 }
+
+func newEngine() (stoic.Stoic, error) {
+	return engine.NewWithOptions(engine.EngineOptions{
+		Root: viper.GetString("root"),
+	})
+}
@@ -2,8 +2,6 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"github.com/stoic-cli/stoic-cli-core/engine"
 )
 
 func init() {
@@ -21,10 +19,7 @@ var runCmd = &cobra.Command{
 }
 
 func run(toolName string, args []string) error {
-	root := viper.GetString("root")
-	engine, err := engine.NewWithOptions(engine.EngineOptions{
-		Root: root,
-	})
+	engine, err := newEngine()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+var (
+	updateAll    bool
+	updateDryRun bool
+)
+
+func init() {
+	updateCmd.Flags().BoolVar(
+		&updateAll, "all", false, "update all configured tools")
+	updateCmd.Flags().BoolVar(
+		&updateDryRun, "dry-run", false, "only report what would change, without downloading release "+
+			"assets (git repositories are still fetched)")
+	rootCmd.AddCommand(updateCmd)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update [tool...]",
+	Short: "Update tools to their latest upstream version",
+	Args:  cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return update(args, updateAll, updateDryRun)
+	},
+}
+
+func update(toolNames []string, all, dryRun bool) error {
+	if all == (len(toolNames) != 0) {
+		return errors.New("specify either tools to update or --all")
+	}
+
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+
+	pinned := map[string]bool{}
+	held := map[string]bool{}
+	for _, t := range engine.Tools() {
+		pinned[t.Name()] = t.IsVersionPinned()
+		held[t.Name()] = t.HeldVersion() != tool.NullVersion
+		if all {
+			toolNames = append(toolNames, t.Name())
+		}
+	}
+	sort.Strings(toolNames)
+
+	suffix := ""
+	if dryRun {
+		suffix = " (dry run)"
+	}
+
+	failed := 0
+	for _, name := range toolNames {
+		from, to, err := engine.UpdateTool(name, dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+			failed++
+			continue
+		}
+
+		switch {
+		case pinned[name]:
+			fmt.Printf("%v: pinned to %v, skipped\n", name, from)
+		case from == to && held[name]:
+			fmt.Printf("%v: held at %v, skipped\n", name, to)
+		case from == to:
+			fmt.Printf("%v: %v is up to date\n", name, to)
+		case from == tool.NullVersion:
+			fmt.Printf("%v: -> %v%v\n", name, to, suffix)
+		default:
+			fmt.Printf("%v: %v -> %v%v\n", name, from, to, suffix)
+		}
+	}
+
+	if failed != 0 {
+		return errors.Errorf("failed to update %d of %d tools", failed, len(toolNames))
+	}
+	return nil
+}
@@ -8,8 +8,6 @@ import (
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"github.com/stoic-cli/stoic-cli-core/engine"
 	"github.com/stoic-cli/stoic-cli-core/tool"
 )
 
@@ -27,10 +25,7 @@ var versionCmd = &cobra.Command{
 }
 
 func version(args []string) error {
-	root := viper.GetString("root")
-	engine, err := engine.NewWithOptions(engine.EngineOptions{
-		Root: root,
-	})
+	engine, err := newEngine()
 	if err != nil {
 		return err
 	}
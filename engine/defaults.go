@@ -4,7 +4,10 @@ import (
 	git "github.com/stoic-cli/stoic-cli-core/get-git"
 	github "github.com/stoic-cli/stoic-cli-core/get-github-release"
 	goget "github.com/stoic-cli/stoic-cli-core/get-go-get"
+	httpget "github.com/stoic-cli/stoic-cli-core/get-http"
+	binary "github.com/stoic-cli/stoic-cli-core/run-binary"
 	gobuild "github.com/stoic-cli/stoic-cli-core/run-go-build"
+	node "github.com/stoic-cli/stoic-cli-core/run-node"
 	python "github.com/stoic-cli/stoic-cli-core/run-python"
 	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
 	"github.com/stoic-cli/stoic-cli-core/tool"
@@ -14,8 +17,11 @@ const (
 	GitGetterType           = "git"
 	GithubReleaseGetterType = "github-release"
 	GoGetGetterType         = "go-get"
+	HTTPGetterType          = "http"
 
+	BinaryRunnerType  = "binary"
 	GoBuildRunnerType = "go-build"
+	NodeRunnerType    = "node"
 	Python2RunnerType = "python2"
 	Python3RunnerType = "python3"
 	PythonRunnerType  = "python"
@@ -33,10 +39,13 @@ func init() {
 	RegisterGetter(GitGetterType, git.NewGetter)
 	RegisterGetter(GithubReleaseGetterType, github.NewGetter)
 	RegisterGetter(GoGetGetterType, goget.NewGetter)
+	RegisterGetter(HTTPGetterType, httpget.NewGetter)
 
 	RegisterRunner(ShellRunnerType, shell.NewRunner)
+	RegisterRunner(BinaryRunnerType, binary.NewRunner)
 	RegisterRunner(PythonRunnerType, python.NewPythonRunner)
 	RegisterRunner(Python2RunnerType, python.NewPython2Runner)
 	RegisterRunner(Python3RunnerType, python.NewPython3Runner)
 	RegisterRunner(GoBuildRunnerType, gobuild.NewRunner)
+	RegisterRunner(NodeRunnerType, node.NewRunner)
 }
@@ -3,6 +3,7 @@ package engine
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -23,6 +24,7 @@ type engine struct {
 	updateFrequencyFallback tool.UpdateFrequency
 	updateFrequencyOverride tool.UpdateFrequency
 
+	hosts map[string]format.HostConfig
 	tools map[string]format.ToolConfig
 }
 
@@ -50,6 +52,7 @@ func NewWithOptions(o EngineOptions) (stoic.Stoic, error) {
 		o.Root = root
 	}
 
+	var hosts map[string]format.HostConfig
 	var tools map[string]format.ToolConfig
 
 	updateFrequencyFallback := DefaultToolUpdateFrequency
@@ -73,6 +76,10 @@ func NewWithOptions(o EngineOptions) (stoic.Stoic, error) {
 		if sc.UpdateFrequency != tool.UpdateDefault {
 			updateFrequencyFallback = sc.UpdateFrequency
 		}
+		hosts = map[string]format.HostConfig{}
+		for host, config := range sc.Hosts {
+			hosts[strings.ToLower(host)] = config
+		}
 		tools = sc.Tools
 	}
 
@@ -85,6 +92,7 @@ func NewWithOptions(o EngineOptions) (stoic.Stoic, error) {
 		updateFrequencyFallback: updateFrequencyFallback,
 		updateFrequencyOverride: o.UpdateFrequency,
 
+		hosts: hosts,
 		tools: tools,
 	}, nil
 }
@@ -96,3 +104,7 @@ func (e *engine) Root() string {
 func (e *engine) ConfigFile() string {
 	return e.configFile
 }
+
+func (e *engine) HostConfig(host string) format.HostConfig {
+	return e.hosts[strings.ToLower(host)]
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+// sharedResourcesGracePeriod protects shared resources that were recently
+// set up or used from being collected, as they may belong to a checkout that
+// another process has yet to record.
+const sharedResourcesGracePeriod = time.Hour
+
+// CollectGarbage removes checkouts of configured tools that are neither
+// current nor pinned, keeping the keep most recent ones for each tool. Shared
+// resources set up by runners (e.g., python virtual environments) that are no
+// longer used by any checkout are removed as well. It returns the number of
+// bytes reclaimed.
+func (e *engine) CollectGarbage(keep int) (int64, error) {
+	var reclaimed int64
+	var failures []string
+
+	toolsByEndpoint := map[string][]stoic.Tool{}
+	for name := range e.tools {
+		t, err := e.getTool(name)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		endpoint := t.Config().Endpoint
+		toolsByEndpoint[endpoint] = append(toolsByEndpoint[endpoint], t)
+	}
+
+	for _, tools := range toolsByEndpoint {
+		n, err := e.collectCheckouts(tools, keep)
+		reclaimed += n
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	n, err := e.collectSharedResources(toolsByEndpoint)
+	reclaimed += n
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) != 0 {
+		return reclaimed, errors.Errorf(
+			"garbage collection incomplete:\n\t%v", strings.Join(failures, "\n\t"))
+	}
+	return reclaimed, nil
+}
+
+// collectCheckouts removes checkouts for tools sharing the same endpoint, and
+// thus the same state.
+func (e *engine) collectCheckouts(tools []stoic.Tool, keep int) (int64, error) {
+	state := tools[0].(engineTool).state.(*toolState)
+
+	lock, err := state.lockCheckouts()
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	state.reload()
+
+	protected := map[string]bool{}
+	if current := state.CurrentCheckout(); current != nil {
+		protected[current.Path()] = true
+	}
+	for _, t := range tools {
+		if t.IsVersionPinned() {
+			pinned := state.CheckoutForVersion(t.Config().PinVersion)
+			if pinned != nil {
+				protected[pinned.Path()] = true
+			}
+		}
+	}
+
+	// Most recent first. Checkouts are appended as they are created, which
+	// settles ties.
+	var checkouts []ToolCheckoutFormat
+	for i := len(state.Checkouts) - 1; i >= 0; i-- {
+		checkouts = append(checkouts, state.Checkouts[i])
+	}
+	sort.SliceStable(checkouts, func(i, j int) bool {
+		return checkouts[i].Created > checkouts[j].Created
+	})
+
+	var reclaimed int64
+	var firstErr error
+
+	removed := map[string]bool{}
+	kept := 0
+	for _, checkout := range checkouts {
+		path := checkout.Path()
+		if protected[path] {
+			continue
+		}
+		if isValidCheckout(checkout) && kept < keep {
+			kept++
+			continue
+		}
+
+		if !e.isInCheckoutsDir(path) {
+			jww.WARN.Printf("not removing checkout outside of %v: %v", e.checkoutsDir, path)
+			continue
+		}
+
+		size := diskUsage(path)
+		if err := os.RemoveAll(path); err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "unable to remove checkout %v", path)
+			}
+			continue
+		}
+
+		jww.INFO.Printf("removed checkout of version '%v' of '%v': %v",
+			checkout.Version(), state.ToolId(), path)
+		reclaimed += size
+		removed[path] = true
+	}
+
+	if len(removed) != 0 {
+		state.removeCheckouts(removed)
+	}
+	return reclaimed, firstErr
+}
+
+// collectSharedResources removes shared resources set up by runners that are
+// not used by any remaining checkout. Nothing is removed if the resources used
+// by any of the checkouts can't be determined.
+func (e *engine) collectSharedResources(toolsByEndpoint map[string][]stoic.Tool) (int64, error) {
+	existing := map[string]bool{}
+	used := map[string]bool{}
+
+	for _, tools := range toolsByEndpoint {
+		for _, t := range tools {
+			r, err := e.runnerFor(t)
+			if err != nil {
+				return 0, errors.Wrapf(err,
+					"unable to determine shared resources used by '%v'", t.Name())
+			}
+
+			runner, ok := r.(tool.SharedResourcesRunner)
+			if !ok {
+				continue
+			}
+
+			paths, err := runner.SharedResources()
+			if err != nil {
+				return 0, errors.Wrapf(err,
+					"unable to list shared resources for '%v'", t.Name())
+			}
+			for _, path := range paths {
+				existing[path] = true
+			}
+
+			state := t.(engineTool).state.(*toolState)
+			state.reload()
+
+			for _, checkout := range state.Checkouts {
+				if !isValidCheckout(checkout) {
+					continue
+				}
+
+				paths, err := runner.SharedResourcesFor(checkout)
+				if err != nil {
+					return 0, errors.Wrapf(err,
+						"unable to determine shared resources used by %v",
+						checkout.Path())
+				}
+				for _, path := range paths {
+					used[path] = true
+				}
+			}
+		}
+	}
+
+	var reclaimed int64
+	var firstErr error
+
+	for path := range existing {
+		if used[path] {
+			continue
+		}
+
+		n, err := collectSharedResource(path)
+		reclaimed += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return reclaimed, firstErr
+}
+
+// collectSharedResource removes the shared resource at path, unless it was
+// recently set up or used. Resources being set up by another process, which
+// holds their lock, are left alone.
+func collectSharedResource(path string) (int64, error) {
+	if !isPastGracePeriod(path) {
+		return 0, nil
+	}
+
+	lock, err := util.TryLockSharedResource(path)
+	if err != nil {
+		jww.DEBUG.Printf("not removing shared resource in use: %v", path)
+		return 0, nil
+	}
+	defer lock.Unlock()
+
+	// The resource may have been set up again while waiting for the lock
+	if !isPastGracePeriod(path) {
+		return 0, nil
+	}
+
+	size := diskUsage(path)
+	if err := os.RemoveAll(path); err != nil {
+		return 0, errors.Wrapf(err, "unable to remove %v", path)
+	}
+
+	jww.INFO.Printf("removed unused shared resource: %v", path)
+	return size, nil
+}
+
+func isPastGracePeriod(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && time.Since(fi.ModTime()) >= sharedResourcesGracePeriod
+}
+
+func (e *engine) isInCheckoutsDir(path string) bool {
+	rel, err := filepath.Rel(e.checkoutsDir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." &&
+		!strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// diskUsage returns the apparent size of files under path.
+func diskUsage(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type sharedResourcesToolRunner struct {
+	noopToolRunner
+
+	root string
+}
+
+func (r sharedResourcesToolRunner) SharedResources() ([]string, error) {
+	return util.GlobSharedResources(filepath.Join(r.root, "*"))
+}
+
+func (r sharedResourcesToolRunner) SharedResourcesFor(checkout tool.Checkout) ([]string, error) {
+	return []string{filepath.Join(r.root, string(checkout.Version()))}, nil
+}
+
+func TestCollectGarbage(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	resourcesRoot := filepath.Join(tid.TestDir(), "resources")
+
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return sharedResourcesToolRunner{root: resourcesRoot}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/test1
+    runner: '%[1]v'
+  pinned:
+    endpoint: github.com/stoic-cli/pinned
+    pin-version: v1
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+	engine := stoic.(*engine)
+
+	addCheckouts := func(toolId string, versions []string, current string) []string {
+		ts := engine.LoadState(toolId).(*toolState)
+
+		var paths []string
+		for _, version := range versions {
+			path := filepath.Join(engine.checkoutsDir, toolId, version)
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatalf("unable to create checkout: %v", err)
+			}
+			err := ioutil.WriteFile(filepath.Join(path, "data"), []byte(version), 0644)
+			if err != nil {
+				t.Fatalf("unable to create checkout: %v", err)
+			}
+
+			ts.addCheckout(tool.Version(version), path, version == current)
+			paths = append(paths, path)
+		}
+		return paths
+	}
+
+	test1 := addCheckouts("github.com/stoic-cli/test1",
+		[]string{"v1", "v2", "v3", "v4", "v5"}, "v3")
+	pinned := addCheckouts("github.com/stoic-cli/pinned",
+		[]string{"v1", "v2", "v3"}, "v3")
+
+	for _, version := range []string{"v1", "v2", "v3", "v4", "v5", "v6", "v7"} {
+		path := filepath.Join(resourcesRoot, version)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("unable to create shared resource: %v", err)
+		}
+
+		old := time.Now().Add(-2 * sharedResourcesGracePeriod)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("unable to backdate shared resource: %v", err)
+		}
+	}
+
+	// Another process is setting up v7
+	lock, err := util.LockSharedResource(filepath.Join(resourcesRoot, "v7"))
+	if err != nil {
+		t.Fatalf("unable to lock shared resource: %v", err)
+	}
+	defer lock.Unlock()
+
+	reclaimed, err := stoic.CollectGarbage(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), reclaimed)
+
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	// Current and most recent are kept
+	assert.False(t, exists(test1[0]))
+	assert.False(t, exists(test1[1]))
+	assert.True(t, exists(test1[2]))
+	assert.False(t, exists(test1[3]))
+	assert.True(t, exists(test1[4]))
+
+	// Pinned, current and most recent are kept
+	assert.True(t, exists(pinned[0]))
+	assert.True(t, exists(pinned[1]))
+	assert.True(t, exists(pinned[2]))
+
+	ts := engine.LoadState("github.com/stoic-cli/test1").(*toolState)
+	if assert.Len(t, ts.Checkouts, 2) {
+		assert.Equal(t, tool.Version("v3"), ts.Checkouts[0].Version())
+		assert.Equal(t, tool.Version("v5"), ts.Checkouts[1].Version())
+	}
+
+	// Resources used by remaining checkouts are kept
+	assert.True(t, exists(filepath.Join(resourcesRoot, "v1")))
+	assert.True(t, exists(filepath.Join(resourcesRoot, "v2")))
+	assert.True(t, exists(filepath.Join(resourcesRoot, "v3")))
+	assert.False(t, exists(filepath.Join(resourcesRoot, "v4")))
+	assert.True(t, exists(filepath.Join(resourcesRoot, "v5")))
+	assert.False(t, exists(filepath.Join(resourcesRoot, "v6")))
+
+	// Resources locked by another process are kept
+	assert.True(t, exists(filepath.Join(resourcesRoot, "v7")))
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+// PrepareError collects the errors for tools that could not be prepared,
+// indexed by tool name.
+type PrepareError map[string]error
+
+func (pe PrepareError) Error() string {
+	var names []string
+	for name := range pe {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "unable to prepare %d tool(s):", len(pe))
+	for _, name := range names {
+		fmt.Fprintf(&builder, "\n\t%v: %v", name, pe[name])
+	}
+	return builder.String()
+}
+
+// Prepare fetches and sets up checkouts for the named tools, or for all
+// configured tools if no names are given, so they are ready to run. Up to
+// concurrency tools are prepared at the same time. Progress is reported per
+// tool, and any failures are returned together in a PrepareError.
+func (e *engine) Prepare(names []string, concurrency int) error {
+	// Sort a copy, leaving the caller's names alone
+	names = append([]string(nil), names...)
+	if len(names) == 0 {
+		for name := range e.tools {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Initialize the cache before it is shared between workers
+	e.Cache()
+
+	var wg sync.WaitGroup
+	var failedLock sync.Mutex
+	failed := PrepareError{}
+
+	queue := make(chan string)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for name := range queue {
+				version, err := e.prepareTool(name)
+				if err != nil {
+					jww.FEEDBACK.Printf("%v: failed\n", name)
+
+					failedLock.Lock()
+					failed[name] = err
+					failedLock.Unlock()
+					continue
+				}
+				jww.FEEDBACK.Printf("%v: ready at %v\n", name, version)
+			}
+		}()
+	}
+
+	for i, name := range names {
+		if i != 0 && name == names[i-1] {
+			continue
+		}
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(failed) != 0 {
+		return failed
+	}
+	return nil
+}
+
+func (e *engine) prepareTool(toolName string) (tool.Version, error) {
+	t, err := e.getTool(toolName)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	getter, err := e.getterFor(t)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	runner, err := e.runnerFor(t)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	checkout, err := e.checkoutForRun(t, getter, runner)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	return checkout.Version(), nil
+}
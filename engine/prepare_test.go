@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type concurrentToolGetter struct {
+	latest tool.Version
+
+	active    *int32
+	maxActive *int32
+}
+
+func (g concurrentToolGetter) FetchLatest() (tool.Version, error) {
+	active := atomic.AddInt32(g.active, 1)
+	defer atomic.AddInt32(g.active, -1)
+
+	for {
+		maxActive := atomic.LoadInt32(g.maxActive)
+		if active <= maxActive ||
+			atomic.CompareAndSwapInt32(g.maxActive, maxActive, active) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if g.latest == tool.NullVersion {
+		return tool.NullVersion, errors.New("no upstream version")
+	}
+	return g.latest, nil
+}
+
+func (g concurrentToolGetter) FetchVersion(tool.Version) error       { return nil }
+func (g concurrentToolGetter) CheckoutTo(tool.Version, string) error { return nil }
+
+type noopToolRunner struct{}
+
+func (noopToolRunner) Setup(tool.Checkout) error                 { return nil }
+func (noopToolRunner) Run(tool.Checkout, string, []string) error { return nil }
+
+func TestPrepare(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var active, maxActive int32
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		latest, _ := t.Config().Getter.Options["latest"].(string)
+		return concurrentToolGetter{tool.Version(latest), &active, &maxActive}, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return noopToolRunner{}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/test1
+    getter: {type: '%[1]v', latest: v1.0.0}
+    runner: '%[1]v'
+  test2:
+    endpoint: github.com/stoic-cli/test2
+    getter: {type: '%[1]v', latest: v2.0.0}
+    runner: '%[1]v'
+  test3:
+    endpoint: github.com/stoic-cli/test3
+    getter: {type: '%[1]v', latest: v3.0.0}
+    runner: '%[1]v'
+  broken:
+    endpoint: github.com/stoic-cli/broken
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+
+	err = stoic.Prepare(nil, 2)
+
+	assert.IsType(t, PrepareError{}, err)
+	if prepareErr, ok := err.(PrepareError); ok {
+		assert.Len(t, prepareErr, 1)
+		assert.Contains(t, prepareErr, "broken")
+	}
+
+	assert.Equal(t, int32(2), maxActive)
+
+	for _, t1 := range stoic.Tools() {
+		if t1.Name() == "broken" {
+			assert.Nil(t, t1.CurrentCheckout())
+			continue
+		}
+
+		checkout := t1.CurrentCheckout()
+		if assert.NotNil(t, checkout) {
+			assert.Equal(t, t1.UpstreamVersion(), checkout.Version())
+		}
+	}
+
+	t.Run("SelectedTools", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		names := []string{"test2", "test1", "test2"}
+		err := stoic.Prepare(names, 4)
+		assert.Nil(err)
+		assert.Equal([]string{"test2", "test1", "test2"}, names)
+
+		err = stoic.Prepare([]string{"test1", "unknown"}, 4)
+		assert.IsType(PrepareError{}, err)
+	})
+}
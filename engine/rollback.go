@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+// RollbackTool marks the checkout that was current before the current one as
+// current again, skipping checkouts of the current version, checkouts that
+// don't satisfy the version constraint of the tool and checkouts that are no
+// longer available. It returns the versions the tool was rolled back
+// from and to.
+//
+// With hold, the version rolled back to is used in place of the current
+// upstream version until a different upstream version becomes available.
+// Otherwise, the tool is updated again once an update is due.
+func (e engine) RollbackTool(toolName string, hold bool) (tool.Version, tool.Version, error) {
+	t, err := e.getTool(toolName)
+	if err != nil {
+		return tool.NullVersion, tool.NullVersion, err
+	}
+
+	if t.IsVersionPinned() {
+		return tool.NullVersion, tool.NullVersion, errors.Errorf(
+			"'%v' is pinned to version '%v'", toolName, t.Config().PinVersion)
+	}
+
+	state := t.(engineTool).state.(*toolState)
+
+	lock, err := state.lockCheckouts()
+	if err != nil {
+		return tool.NullVersion, tool.NullVersion, err
+	}
+	defer lock.Unlock()
+
+	// Pick up changes made while waiting for the lock
+	state.reload()
+
+	history := checkoutsByLastSetCurrent(state.Checkouts)
+	if len(history) == 0 {
+		return tool.NullVersion, tool.NullVersion, errors.Errorf(
+			"no current checkout of '%v' to roll back from", toolName)
+	}
+
+	from := history[0].Version()
+	for _, checkout := range history[1:] {
+		if checkout.Version() == from ||
+			!t.Config().Version.Allows(checkout.Version()) ||
+			!isValidCheckout(checkout) {
+			continue
+		}
+
+		state.setCurrentCheckout(checkout.Path())
+		if hold {
+			state.holdVersion(t.Channel(), checkout.Version())
+		}
+		return from, checkout.Version(), nil
+	}
+
+	return from, tool.NullVersion, errors.Errorf(
+		"no previous checkout of '%v' to roll back to", toolName)
+}
+
+// ToolHistory returns the checkouts recorded for a tool, most recent first.
+func (e engine) ToolHistory(toolName string) ([]stoic.CheckoutRecord, error) {
+	t, err := e.getTool(toolName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := t.(engineTool).state.(*toolState)
+
+	var current string
+	if checkout := t.CurrentCheckout(); checkout != nil {
+		current = checkout.Path()
+	}
+
+	var records []stoic.CheckoutRecord
+	for i := len(state.Checkouts) - 1; i >= 0; i-- {
+		checkout := state.Checkouts[i]
+
+		record := stoic.CheckoutRecord{
+			Checkout:  checkout,
+			Created:   time.Unix(int64(checkout.Created), 0),
+			IsCurrent: checkout.Path() == current,
+		}
+		if checkout.SetCurrent != 0 {
+			record.LastSetCurrent = time.Unix(int64(checkout.SetCurrent), 0)
+		}
+		records = append(records, record)
+	}
+
+	// Checkouts are appended as they are created, which settles ties.
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Created.After(records[j].Created)
+	})
+	return records, nil
+}
+
+// checkoutsByLastSetCurrent returns checkouts that were ever marked as
+// current, most recently marked first. Ties are settled in favor of checkouts
+// created later.
+func checkoutsByLastSetCurrent(checkouts []ToolCheckoutFormat) []ToolCheckoutFormat {
+	var result []ToolCheckoutFormat
+	for i := len(checkouts) - 1; i >= 0; i-- {
+		if checkouts[i].SetCurrent != 0 {
+			result = append(result, checkouts[i])
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].SetCurrent > result[j].SetCurrent
+	})
+	return result
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+type latestToolGetter struct {
+	latest *tool.Version
+}
+
+func (g latestToolGetter) FetchLatest() (tool.Version, error)    { return *g.latest, nil }
+func (g latestToolGetter) FetchVersion(tool.Version) error       { return nil }
+func (g latestToolGetter) CheckoutTo(tool.Version, string) error { return nil }
+
+func TestRollbackTool(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var latest tool.Version
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		return latestToolGetter{&latest}, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return noopToolRunner{}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/test1
+    getter: '%[1]v'
+    runner: '%[1]v'
+  test2:
+    endpoint: github.com/stoic-cli/test2
+    getter: '%[1]v'
+    runner: '%[1]v'
+  pinned:
+    endpoint: github.com/stoic-cli/pinned
+    pin-version: v0.1.0
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+	engine := stoic.(*engine)
+
+	update := func(toolName string, version tool.Version) {
+		latest = version
+		if _, _, err := stoic.UpdateTool(toolName, false); err != nil {
+			t.Fatalf("unable to update '%v' to '%v': %v", toolName, version, err)
+		}
+
+		// Keep the order of checkouts unambiguous
+		backdateCheckouts(engine, "github.com/stoic-cli/"+toolName, time.Hour)
+	}
+
+	update("test1", "v1.0.0")
+	update("test1", "v1.0.0")
+	update("test1", "v2.0.0")
+	update("test2", "v1.0.0")
+
+	t.Run("History", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		records, err := stoic.ToolHistory("test1")
+		assert.Nil(err)
+		if assert.Len(records, 2) {
+			assert.Equal(tool.Version("v2.0.0"), records[0].Version())
+			assert.True(records[0].IsCurrent)
+			assert.Equal(tool.Version("v1.0.0"), records[1].Version())
+			assert.False(records[1].IsCurrent)
+			assert.True(records[0].LastSetCurrent.After(records[1].LastSetCurrent))
+		}
+	})
+	t.Run("RollbackWithHold", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		from, to, err := stoic.RollbackTool("test1", true)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), t1.CurrentVersion())
+		assert.Equal(tool.Version("v1.0.0"), t1.HeldVersion())
+	})
+	t.Run("HoldSurvivesUpdate", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest = "v2.0.0"
+
+		backdateCheckouts(engine, "github.com/stoic-cli/test1", time.Hour)
+
+		from, to, err := stoic.UpdateTool("test1", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), t1.CurrentVersion())
+	})
+	t.Run("HoldReleasedOnUpstreamChange", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest = "v3.0.0"
+
+		from, to, err := stoic.UpdateTool("test1", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), from)
+		assert.Equal(tool.Version("v3.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v3.0.0"), t1.CurrentVersion())
+		assert.Equal(tool.NullVersion, t1.HeldVersion())
+	})
+	t.Run("RollbackWithoutHold", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		backdateCheckouts(engine, "github.com/stoic-cli/test1", time.Hour)
+
+		from, to, err := stoic.RollbackTool("test1", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v3.0.0"), from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), t1.CurrentVersion())
+		assert.Equal(tool.NullVersion, t1.HeldVersion())
+	})
+	t.Run("RunAfterRollbackWithoutHold", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		// The update due on this run moves the tool back to upstream
+		override := engine.updateFrequencyOverride
+		engine.updateFrequencyOverride = tool.UpdateAlways
+		err := stoic.RunTool("test1", nil)
+		engine.updateFrequencyOverride = override
+		assert.Nil(err)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.False(t1.IsUpdateDue())
+		assert.Equal(tool.Version("v3.0.0"), t1.CurrentVersion())
+
+		assert.Nil(stoic.RunTool("test1", nil))
+
+		t1, err = engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v3.0.0"), t1.CurrentVersion())
+	})
+	t.Run("NoPreviousCheckout", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		from, to, err := stoic.RollbackTool("test2", true)
+		assert.NotNil(err)
+		assert.Equal(tool.Version("v1.0.0"), from)
+		assert.Equal(tool.NullVersion, to)
+	})
+	t.Run("RollbackRightAfterUpdate", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest = "v2.0.0"
+
+		_, _, err := stoic.UpdateTool("test2", false)
+		assert.Nil(err)
+
+		from, to, err := stoic.RollbackTool("test2", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t2, err := engine.getTool("test2")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), t2.CurrentVersion())
+	})
+	t.Run("PinnedVersion", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, _, err := stoic.RollbackTool("pinned", true)
+		assert.NotNil(err)
+	})
+	t.Run("UnknownTool", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, _, err := stoic.RollbackTool("unknown", true)
+		assert.NotNil(err)
+		_, err = stoic.ToolHistory("unknown")
+		assert.NotNil(err)
+	})
+}
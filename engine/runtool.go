@@ -34,14 +34,17 @@ func (e engine) makeCheckout(t stoic.Tool, version tool.Version, getter tool.Get
 			version, t.Name())
 	}
 
-	checkoutPath, err := ioutil.TempDir(baseDir, string(version)+"-")
+	// Versions may be hierarchical, as with git tags
+	prefix := strings.Replace(string(version), "/", "_", -1) + "-"
+
+	checkoutPath, err := ioutil.TempDir(baseDir, prefix)
 	if err != nil {
 		return nil, errors.Wrapf(err,
 			"unable to create checkout directory for version '%v' of '%v'",
 			version, t.Name())
 	}
 
-	err = getter.CheckoutTo(version, checkoutPath)
+	err = checkoutTo(t, version, getter, checkoutPath)
 	if err != nil {
 		os.RemoveAll(checkoutPath)
 		return nil, errors.Wrapf(err,
@@ -61,6 +64,47 @@ func (e engine) makeCheckout(t stoic.Tool, version tool.Version, getter tool.Get
 	return checkout, nil
 }
 
+// checkoutTo checks out version into path. If the getter reports that
+// artifacts for the version are no longer available locally, the version is
+// fetched again from upstream and the checkout retried once.
+func checkoutTo(t stoic.Tool, version tool.Version, getter tool.Getter, path string) error {
+	err := getter.CheckoutTo(version, path)
+	if errors.Cause(err) != tool.ErrVersionNotFetched {
+		return err
+	}
+
+	jww.INFO.Printf(
+		"version '%v' of '%v' is missing locally, fetching it again: %v",
+		version, t.Name(), err)
+
+	err = getter.FetchVersion(version)
+	if err != nil {
+		return errors.Wrapf(err,
+			"unable to get version '%v' of '%v' from upstream", version, t.Name())
+	}
+
+	// Discard partial results from the failed checkout
+	err = os.RemoveAll(path)
+	if err == nil {
+		err = os.Mkdir(path, 0700)
+	}
+	if err != nil {
+		return err
+	}
+
+	return getter.CheckoutTo(version, path)
+}
+
+// getCheckout returns an existing checkout for version, making a new one if
+// none is available.
+func (e engine) getCheckout(t stoic.Tool, version tool.Version, getter tool.Getter, runner tool.Runner) (tool.Checkout, error) {
+	checkout := t.CheckoutForVersion(version)
+	if isValidCheckout(checkout) {
+		return checkout, nil
+	}
+	return e.makeCheckout(t, version, getter, runner)
+}
+
 func isValidCheckout(checkout tool.Checkout) bool {
 	if checkout == nil {
 		return false
@@ -74,21 +118,36 @@ func isValidCheckout(checkout tool.Checkout) bool {
 	return true
 }
 
-func (e engine) shouldFetchUpstream(t stoic.Tool) bool {
-	if t.IsVersionPinned() {
-		return false
-	}
-	if t.UpstreamVersion() == tool.NullVersion {
-		return true
+func fetchLatest(t stoic.Tool, getter tool.Getter) (tool.Version, error) {
+	return checkLatest(t, getter.FetchLatest)
+}
+
+// lookupLatest returns the latest upstream version of t. Upstream is only
+// fetched if the getter can't look up versions otherwise.
+func lookupLatest(t stoic.Tool, getter tool.Getter) (tool.Version, error) {
+	if lvg, ok := getter.(tool.LatestVersionGetter); ok {
+		return checkLatest(t, lvg.LatestVersion)
 	}
+	return fetchLatest(t, getter)
+}
 
-	updateFrequency := e.updateFrequencyFallback.Combine(
-		t.UpdateFrequency(), e.updateFrequencyOverride)
-	return updateFrequency.IsTimeToUpdate(t.LastUpdate())
+// checkLatest returns the latest upstream version from latest, checking that
+// it's usable for t.
+func checkLatest(t stoic.Tool, latest func() (tool.Version, error)) (tool.Version, error) {
+	version, err := latest()
+	if err == nil && version == tool.NullVersion {
+		err = errors.New("upstream version is empty")
+	}
+	if err == nil && !t.Config().Version.Allows(version) {
+		err = errors.Errorf(
+			"upstream version '%v' doesn't satisfy version constraint '%v'",
+			version, t.Config().Version)
+	}
+	return version, err
 }
 
 func (e engine) getVersionForCheckout(t stoic.Tool, getter tool.Getter) (tool.Version, error) {
-	if !e.shouldFetchUpstream(t) {
+	if !t.IsUpdateDue() {
 		checkout := t.CurrentCheckout()
 		if checkout != nil {
 			return checkout.Version(), nil
@@ -107,15 +166,12 @@ func (e engine) getVersionForCheckout(t stoic.Tool, getter tool.Getter) (tool.Ve
 		return pinVersion, nil
 	}
 
-	version, err := getter.FetchLatest()
+	version, err := fetchLatest(t, getter)
 	if err == nil {
-		if version == tool.NullVersion {
-			err = errors.New("upstream version is empty")
-		} else {
-			t.(engineTool).state.(*toolState).setUpstreamVersion(t.Channel(), version)
-		}
-	}
-	if err != nil {
+		state := t.(engineTool).state.(*toolState)
+		state.setUpstreamVersion(t.Channel(), version)
+		version = state.applyHold(t.Channel(), version, t.Config().Version)
+	} else {
 		jww.WARN.Printf(
 			"unable to get upstream version of %v: %v", t.Name(), err)
 
@@ -131,6 +187,61 @@ func (e engine) getVersionForCheckout(t stoic.Tool, getter tool.Getter) (tool.Ve
 	return version, nil
 }
 
+// reusableCheckout returns the current checkout of t if it is valid and no
+// upstream update is due.
+func reusableCheckout(t stoic.Tool) tool.Checkout {
+	if t.IsUpdateDue() {
+		return nil
+	}
+
+	checkout := t.CurrentCheckout()
+	if !isValidCheckout(checkout) {
+		return nil
+	}
+	return checkout
+}
+
+// checkoutForRun returns the checkout of t that should be run, fetching from
+// upstream and setting up a new checkout as needed. Fetching and setting up
+// checkouts is serialized across processes. A process that had to wait reuses
+// the checkout set up in the meantime, if suitable.
+func (e engine) checkoutForRun(t stoic.Tool, getter tool.Getter, runner tool.Runner) (tool.Checkout, error) {
+	if checkout := reusableCheckout(t); checkout != nil {
+		return checkout, nil
+	}
+
+	state := t.(engineTool).state.(*toolState)
+
+	lock, err := state.lockCheckouts()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	// Pick up changes made while waiting for the lock
+	state.reload()
+	if checkout := reusableCheckout(t); checkout != nil {
+		return checkout, nil
+	}
+
+	version, err := e.getVersionForCheckout(t, getter)
+	if err != nil {
+		return nil, err
+	}
+
+	checkout, err := e.getCheckout(t, version, getter, runner)
+	if err != nil {
+		return nil, err
+	}
+
+	// An existing checkout may be reused, as after a rollback
+	current := t.CurrentCheckout()
+	if current == nil || current.Path() != checkout.Path() {
+		state.setCurrentCheckout(checkout.Path())
+	}
+	return checkout, nil
+}
+
 func (e engine) RunTool(toolName string, args []string) error {
 	t, err := e.getTool(toolName)
 	if err != nil {
@@ -146,19 +257,10 @@ func (e engine) RunTool(toolName string, args []string) error {
 		return err
 	}
 
-	version, err := e.getVersionForCheckout(t, getter)
+	checkout, err := e.checkoutForRun(t, getter, runner)
 	if err != nil {
 		return err
 	}
 
-	checkout := t.CheckoutForVersion(version)
-	if !isValidCheckout(checkout) {
-		checkout, err = e.makeCheckout(t, version, getter, runner)
-		// FIXME: When cached artifacts are evicted, new checkouts fail
-		if err != nil {
-			return err
-		}
-	}
-
 	return runner.Run(checkout, toolName, args)
 }
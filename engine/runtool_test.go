@@ -2,9 +2,14 @@ package engine
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stoic-cli/stoic-cli-core"
 	"github.com/stoic-cli/stoic-cli-core/tool"
 	"github.com/stoic-cli/stoic-cli-core/util"
@@ -126,3 +131,159 @@ tools:
 	assert.Equal(t, uint(1), mtr_Setup_callCount)
 	assert.Equal(t, uint(1), mtr_Run_callCount)
 }
+
+func TestRunToolRefetchesEvictedVersion(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var fetchLatestCount, fetchVersionCount, checkoutToCount uint
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		cacheKey := "test/" + t.Name()
+		put := func() error {
+			return s.Cache().Put(cacheKey, strings.NewReader("artifact"))
+		}
+
+		return &mockToolGetter{
+			FetchLatestFunc: func() (tool.Version, error) {
+				fetchLatestCount += 1
+				if err := put(); err != nil {
+					return tool.NullVersion, err
+				}
+
+				// Evict artifact before checkout
+				s.Cache().(*diskvCache).diskv.Erase(cacheKey)
+				return tool.Version("v1.0.0"), nil
+			},
+			FetchVersionFunc: func(tool.Version) error {
+				fetchVersionCount += 1
+				return put()
+			},
+			CheckoutToFunc: func(version tool.Version, path string) error {
+				checkoutToCount += 1
+				reader := s.Cache().Get(cacheKey)
+				if reader == nil {
+					return errors.Wrap(tool.ErrVersionNotFetched, "cache miss")
+				}
+				return reader.Close()
+			},
+		}, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return &mockToolRunner{}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/stoic-cli-core
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+
+	err = stoic.RunTool("test1", nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, uint(1), fetchLatestCount)
+	assert.Equal(t, uint(1), fetchVersionCount)
+	assert.Equal(t, uint(2), checkoutToCount)
+}
+
+func TestRunToolReusesConcurrentCheckout(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var fetchCount, checkoutToCount uint
+	var runCheckout tool.Checkout
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		return &mockToolGetter{
+			FetchLatestFunc: func() (tool.Version, error) {
+				fetchCount += 1
+				return tool.Version("v2.0.0"), nil
+			},
+			FetchVersionFunc: func(tool.Version) error {
+				fetchCount += 1
+				return nil
+			},
+			CheckoutToFunc: func(tool.Version, string) error {
+				checkoutToCount += 1
+				return nil
+			},
+		}, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return &mockToolRunner{
+			RunFunc: func(checkout tool.Checkout, name string, args []string) error {
+				runCheckout = checkout
+				return nil
+			},
+		}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/stoic-cli-core
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+
+	// Simulate another process setting up a checkout
+	otherState := stoic.(*engine).LoadState(
+		"github.com/stoic-cli/stoic-cli-core").(*toolState)
+	otherLock, err := otherState.lockCheckouts()
+	if err != nil {
+		t.Fatalf("unable to lock checkouts: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- stoic.RunTool("test1", nil)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("RunTool did not wait for lock on checkouts: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	otherCheckout := filepath.Join(tid.TestDir(), "other-checkout")
+	err = os.Mkdir(otherCheckout, 0755)
+	if err != nil {
+		t.Fatalf("unable to create checkout: %v", err)
+	}
+
+	otherState.setUpstreamVersion(tool.DefaultChannel, tool.Version("v1.0.0"))
+	otherState.addCheckout(tool.Version("v1.0.0"), otherCheckout, true)
+	otherLock.Unlock()
+
+	assert.Nil(t, <-done)
+
+	assert.Equal(t, uint(0), fetchCount)
+	assert.Equal(t, uint(0), checkoutToCount)
+	if assert.NotNil(t, runCheckout) {
+		assert.Equal(t, otherCheckout, runCheckout.Path())
+		assert.Equal(t, tool.Version("v1.0.0"), runCheckout.Version())
+	}
+}
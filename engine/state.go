@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/url"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/pkg/errors"
 	jww "github.com/spf13/jwalterweatherman"
 	"github.com/stoic-cli/stoic-cli-core/tool"
 	"github.com/stoic-cli/stoic-cli-core/util"
@@ -15,6 +17,10 @@ import (
 
 type UnixTimestamp int64
 
+const (
+	checkoutsLockTimeout = 15 * time.Minute
+)
+
 // State represents metadata persisted by the engine for a given tool.
 type State interface {
 	// ToolId identifies the tool the instance pertains too.
@@ -37,6 +43,10 @@ type State interface {
 	// CheckoutForVersion returns the most recent checkout for the requested
 	// version.
 	CheckoutForVersion(version tool.Version) tool.Checkout
+
+	// HeldVersion returns the version held in place of the specified upstream
+	// version of the channel, if any.
+	HeldVersion(tc tool.Channel, upstream tool.Version) tool.Version
 }
 
 func (e engine) LoadState(toolId string) State {
@@ -45,35 +55,68 @@ func (e engine) LoadState(toolId string) State {
 		toolId:   toolId,
 		filename: filename,
 	}
+	state.reload()
+	return state
+}
+
+type toolState struct {
+	toolId   string
+	filename string
 
-	stateFile, err := os.Open(filename)
+	ToolStateFormat
+}
+
+// reload discards local state and loads it anew from the state file.
+func (ts *toolState) reload() {
+	ts.ToolStateFormat = ToolStateFormat{}
+
+	stateFile, err := os.Open(ts.filename)
 	if os.IsNotExist(err) {
 		// No previous state
-		return state
+		return
 	}
 	if err != nil {
-		jww.WARN.Printf("Unable to load state from %v: %v", filename, err)
-		return state
+		jww.WARN.Printf("Unable to load state from %v: %v", ts.filename, err)
+		return
 	}
 	defer stateFile.Close()
 
-	err = state.ToolStateFormat.load(stateFile)
+	err = ts.ToolStateFormat.load(stateFile)
 	if err != nil {
-		jww.WARN.Printf("Unable to load state from %v, is file corrupt? %v", filename, err)
+		jww.WARN.Printf("Unable to load state from %v, is file corrupt? %v", ts.filename, err)
 	}
-	return state
 }
 
-type toolState struct {
-	toolId   string
-	filename string
+// lockCheckouts acquires a lock serializing upstream fetches and changes to
+// checkouts of the tool across processes. It waits for up to
+// checkoutsLockTimeout for a lock held by another process to be released.
+func (ts *toolState) lockCheckouts() (util.FileLock, error) {
+	lockedFile := ts.filename + ".checkouts"
 
-	ToolStateFormat
+	err := os.MkdirAll(filepath.Dir(lockedFile), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := util.LockFile(context.Background(), lockedFile, util.LockOptions{
+		Timeout: checkoutsLockTimeout,
+		Flock:   true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"unable to obtain lock on checkouts of '%v'", ts.toolId)
+	}
+	return lock, nil
 }
 
 func (ts *toolState) updateInTransaction(updateFunc func(UnixTimestamp)) (err error) {
 	timestamp := UnixTimestamp(time.Now().Unix())
 
+	// Serialize transactions on the state file within the process
+	stateMutex := util.PathMutex(ts.filename)
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
 	err = os.MkdirAll(filepath.Dir(ts.filename), 0755)
 	if err != nil {
 		goto updateFailedEarly
@@ -89,8 +132,9 @@ func (ts *toolState) updateInTransaction(updateFunc func(UnixTimestamp)) (err er
 		}
 		defer stateFile.AbortIfPending()
 
-		// Load current state
+		// Load current state, which may have been changed by other processes
 		if curr := stateFile.Current(); curr != nil {
+			ts.ToolStateFormat = ToolStateFormat{}
 			err = ts.ToolStateFormat.load(curr)
 			if err != nil {
 				goto updateFailedEarly
@@ -145,7 +189,7 @@ func (ts *toolState) addCheckout(tv tool.Version, path string, setCurrent bool)
 			Created:         timestamp,
 		}
 		if setCurrent {
-			checkout.SetCurrent = timestamp
+			checkout.SetCurrent = ts.nextSetCurrent(timestamp)
 		}
 
 		ts.Checkouts = append(ts.Checkouts, checkout)
@@ -158,7 +202,7 @@ func (ts *toolState) setCurrentCheckout(path string) {
 	err := ts.updateInTransaction(func(timestamp UnixTimestamp) {
 		for i := range ts.Checkouts {
 			if ts.Checkouts[i].CheckoutPath == path {
-				ts.Checkouts[i].SetCurrent = timestamp
+				ts.Checkouts[i].SetCurrent = ts.nextSetCurrent(timestamp)
 				return
 			}
 		}
@@ -171,6 +215,61 @@ func (ts *toolState) setCurrentCheckout(path string) {
 	}
 }
 
+// holdVersion keeps tv in use in place of the current upstream version of the
+// channel, until a different upstream version becomes available.
+func (ts *toolState) holdVersion(tc tool.Channel, tv tool.Version) {
+	err := ts.updateInTransaction(func(timestamp UnixTimestamp) {
+		ts.Hold = &ToolHoldFormat{
+			Channel:  tc,
+			Version:  tv,
+			Upstream: ts.UpstreamVersion(tc),
+			Created:  timestamp,
+		}
+	})
+	if err != nil {
+		jww.ERROR.Printf("Unable to persist hold on version to %v: %v", ts.filename, err)
+	}
+}
+
+// applyHold returns the version to use in place of the upstream version of the
+// channel. A hold on the channel that was placed against a different upstream
+// version, or on a version that doesn't satisfy constraint, is released.
+func (ts *toolState) applyHold(tc tool.Channel, upstream tool.Version, constraint tool.VersionConstraint) tool.Version {
+	held := ts.HeldVersion(tc, upstream)
+	if held != tool.NullVersion && constraint.Allows(held) {
+		return held
+	}
+	if ts.Hold == nil || ts.Hold.Channel != tc {
+		return upstream
+	}
+
+	jww.INFO.Printf("releasing hold on version '%v' of '%v', upstream is now at '%v'",
+		ts.Hold.Version, ts.toolId, upstream)
+
+	err := ts.updateInTransaction(func(UnixTimestamp) {
+		ts.Hold = nil
+	})
+	if err != nil {
+		jww.ERROR.Printf("Unable to persist release of hold on version to %v: %v", ts.filename, err)
+	}
+	return upstream
+}
+
+func (ts *toolState) removeCheckouts(paths map[string]bool) {
+	err := ts.updateInTransaction(func(timestamp UnixTimestamp) {
+		var checkouts []ToolCheckoutFormat
+		for _, checkout := range ts.Checkouts {
+			if !paths[checkout.CheckoutPath] {
+				checkouts = append(checkouts, checkout)
+			}
+		}
+		ts.Checkouts = checkouts
+	})
+	if err != nil {
+		jww.ERROR.Printf("Unable to persist removal of checkouts to %v: %v", ts.filename, err)
+	}
+}
+
 func (ts *toolState) ToolId() string   { return ts.toolId }
 func (ts *toolState) Filename() string { return ts.filename }
 
@@ -179,6 +278,7 @@ type ToolStateFormat struct {
 	Upstream  *ToolChannelInfoFormat                 `json:"upstream,omitempty"`
 	Channels  map[tool.Channel]ToolChannelInfoFormat `json:"channels,omitempty"`
 	Checkouts []ToolCheckoutFormat                   `json:"checkouts,omitempty"`
+	Hold      *ToolHoldFormat                        `json:"hold,omitempty"`
 }
 
 func (tsf *ToolStateFormat) load(r io.Reader) error {
@@ -216,54 +316,61 @@ func (tsf *ToolStateFormat) LastUpstreamUpdate(tc tool.Channel) time.Time {
 	return time.Time{}
 }
 
+// CurrentCheckout returns the checkout most recently marked as current. Ties
+// are settled in favor of checkouts recorded later.
 func (tsf *ToolStateFormat) CurrentCheckout() tool.Checkout {
-	if tsf.Checkouts == nil {
-		return nil
-	}
-
 	youngest := -1
-	for i := range tsf.Checkouts {
-		if tsf.Checkouts[i].SetCurrent != 0 {
+	for i, checkout := range tsf.Checkouts {
+		if checkout.SetCurrent == 0 {
+			continue
+		}
+		if youngest == -1 || tsf.Checkouts[youngest].SetCurrent <= checkout.SetCurrent {
 			youngest = i
-			break
 		}
 	}
+
 	if youngest == -1 {
 		return nil
 	}
+	return tsf.Checkouts[youngest]
+}
 
-	for i := range tsf.Checkouts[youngest:] {
-		if tsf.Checkouts[youngest].SetCurrent < tsf.Checkouts[i].SetCurrent {
-			youngest = i
+// nextSetCurrent returns the timestamp at which to mark a checkout as current,
+// which is after those of checkouts marked earlier, even within the same
+// second.
+func (tsf *ToolStateFormat) nextSetCurrent(timestamp UnixTimestamp) UnixTimestamp {
+	for _, checkout := range tsf.Checkouts {
+		if checkout.SetCurrent >= timestamp {
+			timestamp = checkout.SetCurrent + 1
 		}
 	}
-	return tsf.Checkouts[youngest]
+	return timestamp
 }
 
+// CheckoutForVersion returns the most recently created checkout for version.
+// Ties are settled in favor of checkouts recorded later.
 func (tsf *ToolStateFormat) CheckoutForVersion(version tool.Version) tool.Checkout {
-	if tsf.Checkouts == nil {
-		return nil
-	}
-
 	youngest := -1
-	for i := range tsf.Checkouts {
-		if tsf.Checkouts[i].CheckoutVersion == version {
+	for i, checkout := range tsf.Checkouts {
+		if checkout.CheckoutVersion != version {
+			continue
+		}
+		if youngest == -1 || tsf.Checkouts[youngest].Created <= checkout.Created {
 			youngest = i
-			break
 		}
 	}
+
 	if youngest == -1 {
 		return nil
 	}
+	return tsf.Checkouts[youngest]
+}
 
-	for i := range tsf.Checkouts[youngest:] {
-		if tsf.Checkouts[i].CheckoutVersion == version {
-			if tsf.Checkouts[youngest].Created < tsf.Checkouts[i].Created {
-				youngest = i
-			}
-		}
+func (tsf *ToolStateFormat) HeldVersion(tc tool.Channel, upstream tool.Version) tool.Version {
+	if tsf.Hold == nil || tsf.Hold.Channel != tc || tsf.Hold.Upstream != upstream {
+		return tool.NullVersion
 	}
-	return tsf.Checkouts[youngest]
+	return tsf.Hold.Version
 }
 
 // ToolChannelInfoFormat defines the low-level format for persisting information about
@@ -282,5 +389,15 @@ type ToolCheckoutFormat struct {
 	SetCurrent      UnixTimestamp `json:"set-current,omitempty"`
 }
 
+// ToolHoldFormat defines the low-level format for persisting a hold on a
+// version of a tool, which is used in place of the upstream version the hold
+// was placed against.
+type ToolHoldFormat struct {
+	Channel  tool.Channel  `json:"channel,omitempty"`
+	Version  tool.Version  `json:"version"`
+	Upstream tool.Version  `json:"upstream,omitempty"`
+	Created  UnixTimestamp `json:"created"`
+}
+
 func (tcf ToolCheckoutFormat) Path() string          { return tcf.CheckoutPath }
 func (tcf ToolCheckoutFormat) Version() tool.Version { return tcf.CheckoutVersion }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stoic-cli/stoic-cli-core/tool"
 	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestEngineState(t *testing.T) {
@@ -68,6 +69,27 @@ func TestEngineState(t *testing.T) {
 		assert.Equal("checkout-v1-xyz", checkout.Path())
 		assert.Equal(tool.Version("1"), checkout.Version())
 	})
+	t.Run("ChangesByOtherProcesses", func(t *testing.T) {
+		assert, testName := tid.SetupTest(t)
+
+		ts := engine.LoadState(testName).(*toolState)
+		ts.addCheckout(tool.Version("1"), "checkout-v1-xyz", true)
+		ts.holdVersion(tool.DefaultChannel, tool.Version("1"))
+
+		// Another process releases the hold and removes the checkout
+		other := engine.LoadState(testName).(*toolState)
+		other.applyHold(tool.DefaultChannel, tool.Version("2"), tool.VersionConstraint(""))
+		other.removeCheckouts(map[string]bool{"checkout-v1-xyz": true})
+
+		ts.setUpstreamVersion(tool.DefaultChannel, tool.Version("2"))
+		assert.Nil(ts.Hold)
+		assert.Empty(ts.Checkouts)
+
+		state := engine.LoadState(testName)
+		assert.Equal(tool.Version("2"), state.UpstreamVersion(tool.DefaultChannel))
+		assert.Nil(state.CurrentCheckout())
+		assert.Equal(tool.NullVersion, state.HeldVersion(tool.DefaultChannel, tool.Version("2")))
+	})
 	t.Run("AlternateChannelFlow", func(t *testing.T) {
 		assert, testName := tid.SetupTest(t)
 
@@ -99,3 +121,154 @@ func TestEngineState(t *testing.T) {
 		assert.Equal(tool.Version("1"), checkout.Version())
 	})
 }
+
+func TestToolStateFormatCurrentCheckout(t *testing.T) {
+	data := []struct {
+		Name      string
+		Checkouts []ToolCheckoutFormat
+		Path      string
+	}{
+		{
+			Name: "NoCheckouts",
+		},
+		{
+			Name: "NoneCurrent",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20},
+			},
+		},
+		{
+			Name: "SingleCurrent",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10, SetCurrent: 10},
+			},
+			Path: "v1",
+		},
+		{
+			Name: "FirstCurrentNotAtStart",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20, SetCurrent: 20},
+				{CheckoutVersion: "3", CheckoutPath: "v3", Created: 30},
+				{CheckoutVersion: "4", CheckoutPath: "v4", Created: 40, SetCurrent: 40},
+			},
+			Path: "v4",
+		},
+		{
+			Name: "OlderCheckoutSetCurrentLater",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10, SetCurrent: 50},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20, SetCurrent: 20},
+				{CheckoutVersion: "3", CheckoutPath: "v3", Created: 30, SetCurrent: 30},
+			},
+			Path: "v1",
+		},
+		{
+			Name: "YoungestInTheMiddle",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20, SetCurrent: 20},
+				{CheckoutVersion: "3", CheckoutPath: "v3", Created: 30, SetCurrent: 60},
+				{CheckoutVersion: "4", CheckoutPath: "v4", Created: 40, SetCurrent: 40},
+			},
+			Path: "v3",
+		},
+		{
+			Name: "TieGoesToLaterCheckout",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20, SetCurrent: 30},
+				{CheckoutVersion: "3", CheckoutPath: "v3", Created: 30, SetCurrent: 30},
+			},
+			Path: "v3",
+		},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			tsf := ToolStateFormat{Checkouts: test.Checkouts}
+
+			checkout := tsf.CurrentCheckout()
+			if test.Path == "" {
+				assert.Nil(checkout)
+			} else if assert.NotNil(checkout) {
+				assert.Equal(test.Path, checkout.Path())
+			}
+		})
+	}
+}
+
+func TestToolStateFormatCheckoutForVersion(t *testing.T) {
+	interleaved := []ToolCheckoutFormat{
+		{CheckoutVersion: "2", CheckoutPath: "v2-a", Created: 10},
+		{CheckoutVersion: "1", CheckoutPath: "v1-a", Created: 20, SetCurrent: 20},
+		{CheckoutVersion: "2", CheckoutPath: "v2-b", Created: 30},
+		{CheckoutVersion: "1", CheckoutPath: "v1-b", Created: 40},
+		{CheckoutVersion: "3", CheckoutPath: "v3-a", Created: 50, SetCurrent: 50},
+		{CheckoutVersion: "2", CheckoutPath: "v2-c", Created: 25},
+		{CheckoutVersion: "3", CheckoutPath: "v3-b", Created: 50},
+	}
+
+	data := []struct {
+		Name      string
+		Checkouts []ToolCheckoutFormat
+		Version   tool.Version
+		Path      string
+	}{
+		{
+			Name:    "NoCheckouts",
+			Version: "1",
+		},
+		{
+			Name:      "UnknownVersion",
+			Checkouts: interleaved,
+			Version:   "4",
+		},
+		{
+			Name: "SingleMatch",
+			Checkouts: []ToolCheckoutFormat{
+				{CheckoutVersion: "1", CheckoutPath: "v1", Created: 10},
+				{CheckoutVersion: "2", CheckoutPath: "v2", Created: 20},
+			},
+			Version: "2",
+			Path:    "v2",
+		},
+		{
+			Name:      "FirstMatchNotAtStart",
+			Checkouts: interleaved,
+			Version:   "1",
+			Path:      "v1-b",
+		},
+		{
+			Name:      "YoungestNotLast",
+			Checkouts: interleaved,
+			Version:   "2",
+			Path:      "v2-b",
+		},
+		{
+			Name:      "TieGoesToLaterCheckout",
+			Checkouts: interleaved,
+			Version:   "3",
+			Path:      "v3-b",
+		},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			tsf := ToolStateFormat{Checkouts: test.Checkouts}
+
+			checkout := tsf.CheckoutForVersion(test.Version)
+			if test.Path == "" {
+				assert.Nil(checkout)
+			} else if assert.NotNil(checkout) {
+				assert.Equal(test.Path, checkout.Path())
+				assert.Equal(test.Version, checkout.Version())
+			}
+		})
+	}
+}
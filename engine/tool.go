@@ -27,10 +27,11 @@ func (e *engine) Tools() []stoic.Tool {
 }
 
 type engineTool struct {
-	name     string
-	endpoint *url.URL
-	config   format.ToolConfig
-	state    State
+	name            string
+	endpoint        *url.URL
+	config          format.ToolConfig
+	updateFrequency tool.UpdateFrequency
+	state           State
 }
 
 func (e *engine) getTool(name string) (stoic.Tool, error) {
@@ -55,12 +56,16 @@ func (e *engine) getTool(name string) (stoic.Tool, error) {
 		config.Runner.Type = DefaultToolRunnerType
 	}
 
+	updateFrequency := e.updateFrequencyFallback.Combine(
+		config.UpdateFrequency, e.updateFrequencyOverride)
+
 	state := e.LoadState(config.Endpoint)
 	return engineTool{
-		name:     name,
-		endpoint: url,
-		config:   config,
-		state:    state,
+		name:            name,
+		endpoint:        url,
+		config:          config,
+		updateFrequency: updateFrequency,
+		state:           state,
 	}, nil
 }
 
@@ -74,7 +79,20 @@ func (t engineTool) IsVersionPinned() bool {
 }
 
 func (t engineTool) UpdateFrequency() tool.UpdateFrequency {
-	return t.config.UpdateFrequency
+	return t.updateFrequency
+}
+
+func (t engineTool) IsUpdateDue() bool {
+	if t.IsVersionPinned() {
+		return false
+	}
+	if t.UpstreamVersion() == tool.NullVersion {
+		return true
+	}
+	if !t.SatisfiesVersionConstraint() {
+		return true
+	}
+	return t.UpdateFrequency().IsTimeToUpdate(t.LastUpdate())
 }
 
 func (t engineTool) UpstreamVersion() tool.Version {
@@ -97,6 +115,21 @@ func (t engineTool) CurrentVersion() tool.Version {
 	return checkout.Version()
 }
 
+// SatisfiesVersionConstraint is true if the current version satisfies the
+// version constraint of the tool, or if there is no constraint.
+func (t engineTool) SatisfiesVersionConstraint() bool {
+	return t.config.Version.Allows(t.CurrentVersion())
+}
+
+// HeldVersion returns the version held in place of the current upstream
+// version, if any. Holds don't apply to tools with a pinned version.
+func (t engineTool) HeldVersion() tool.Version {
+	if t.IsVersionPinned() {
+		return tool.NullVersion
+	}
+	return t.state.HeldVersion(t.Channel(), t.UpstreamVersion())
+}
+
 func (t engineTool) CurrentCheckout() tool.Checkout {
 	if t.IsVersionPinned() {
 		return t.CheckoutForVersion(t.config.PinVersion)
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+func TestToolIsUpdateDue(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	err := ioutil.WriteFile("config", []byte(`
+update: never
+tools:
+  fresh:
+    endpoint: github.com/stoic-cli/fresh
+  pinned:
+    endpoint: github.com/stoic-cli/pinned
+    pin-version: v1.0.0
+  daily:
+    endpoint: github.com/stoic-cli/daily
+    update: daily
+  default:
+    endpoint: github.com/stoic-cli/default
+  constrained:
+    endpoint: github.com/stoic-cli/constrained
+    version: ^2
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+	engine := stoic.(*engine)
+
+	for _, name := range []string{"pinned", "daily", "default", "constrained"} {
+		engine.LoadState(engine.tools[name].Endpoint).(*toolState).
+			setUpstreamVersion(tool.DefaultChannel, tool.Version("v1.0.0"))
+	}
+
+	t.Run("NeverFetched", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		fresh, err := engine.getTool("fresh")
+		assert.Nil(err)
+		assert.Equal(tool.UpdateNever, fresh.UpdateFrequency())
+		assert.True(fresh.IsUpdateDue())
+	})
+	t.Run("Pinned", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		pinned, err := engine.getTool("pinned")
+		assert.Nil(err)
+		assert.False(pinned.IsUpdateDue())
+	})
+	t.Run("ToolFrequency", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		daily, err := engine.getTool("daily")
+		assert.Nil(err)
+		assert.Equal(tool.UpdateDaily, daily.UpdateFrequency())
+		assert.False(daily.IsUpdateDue())
+	})
+	t.Run("FallbackFrequency", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		dflt, err := engine.getTool("default")
+		assert.Nil(err)
+		assert.Equal(tool.UpdateNever, dflt.UpdateFrequency())
+		assert.False(dflt.IsUpdateDue())
+	})
+	t.Run("OverrideFrequency", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		engine.updateFrequencyOverride = tool.UpdateAlways
+		defer func() { engine.updateFrequencyOverride = tool.UpdateDefault }()
+
+		daily, err := engine.getTool("daily")
+		assert.Nil(err)
+		assert.Equal(tool.UpdateAlways, daily.UpdateFrequency())
+		assert.True(daily.IsUpdateDue())
+	})
+	t.Run("VersionConstraint", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		constrained, err := engine.getTool("constrained")
+		assert.Nil(err)
+		assert.False(constrained.SatisfiesVersionConstraint())
+		assert.True(constrained.IsUpdateDue())
+
+		constrained.(engineTool).state.(*toolState).
+			addCheckout(tool.Version("v2.1.0"), "v2.1.0", true)
+
+		assert.True(constrained.SatisfiesVersionConstraint())
+		assert.False(constrained.IsUpdateDue())
+	})
+}
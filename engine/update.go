@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+// UpdateTool fetches the latest upstream version of a tool, regardless of its
+// update frequency, and sets up a checkout for it as the current one. It
+// returns the versions the tool was updated from and to.
+//
+// A version held by RollbackTool is kept for as long as the upstream version
+// doesn't change. With dryRun, the upstream version is looked up but neither
+// recorded nor checked out. Getters that can't look up versions without
+// fetching them (e.g., git) still fetch from upstream. Tools with a pinned
+// version are left unchanged.
+func (e engine) UpdateTool(toolName string, dryRun bool) (tool.Version, tool.Version, error) {
+	t, err := e.getTool(toolName)
+	if err != nil {
+		return tool.NullVersion, tool.NullVersion, err
+	}
+
+	from := t.CurrentVersion()
+	if t.IsVersionPinned() {
+		return from, from, nil
+	}
+
+	getter, err := e.getterFor(t)
+	if err != nil {
+		return from, tool.NullVersion, err
+	}
+	runner, err := e.runnerFor(t)
+	if err != nil {
+		return from, tool.NullVersion, err
+	}
+
+	state := t.(engineTool).state.(*toolState)
+
+	lock, err := state.lockCheckouts()
+	if err != nil {
+		return from, tool.NullVersion, err
+	}
+	defer lock.Unlock()
+
+	// Pick up changes made while waiting for the lock
+	state.reload()
+	from = t.CurrentVersion()
+
+	var to tool.Version
+	if dryRun {
+		to, err = lookupLatest(t, getter)
+	} else {
+		to, err = fetchLatest(t, getter)
+	}
+	if err != nil {
+		return from, tool.NullVersion, errors.Wrapf(err,
+			"unable to get upstream version of '%v'", toolName)
+	}
+	if dryRun {
+		held := state.HeldVersion(t.Channel(), to)
+		if held != tool.NullVersion && t.Config().Version.Allows(held) {
+			to = held
+		}
+		return from, to, nil
+	}
+
+	state.setUpstreamVersion(t.Channel(), to)
+	to = state.applyHold(t.Channel(), to, t.Config().Version)
+
+	checkout, err := e.getCheckout(t, to, getter, runner)
+	if err != nil {
+		return from, tool.NullVersion, err
+	}
+
+	current := t.CurrentCheckout()
+	if current == nil || current.Path() != checkout.Path() {
+		state.setCurrentCheckout(checkout.Path())
+	}
+
+	return from, to, nil
+}
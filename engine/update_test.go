@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateTool(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var latest tool.Version
+	var latestErr error
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		return &mockToolGetter{
+			FetchLatestFunc: func() (tool.Version, error) {
+				return latest, latestErr
+			},
+		}, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return &mockToolRunner{}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/stoic-cli-core
+    getter: '%[1]v'
+    runner: '%[1]v'
+  pinned:
+    endpoint: github.com/stoic-cli/pinned
+    pin-version: v0.1.0
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+	engine := stoic.(*engine)
+
+	t.Run("DryRun", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest, latestErr = tool.Version("v1.0.0"), nil
+
+		from, to, err := stoic.UpdateTool("test1", true)
+		assert.Nil(err)
+		assert.Equal(tool.NullVersion, from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.NullVersion, t1.UpstreamVersion())
+		assert.Nil(t1.CurrentCheckout())
+	})
+	t.Run("InitialUpdate", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest, latestErr = tool.Version("v1.0.0"), nil
+
+		from, to, err := stoic.UpdateTool("test1", false)
+		assert.Nil(err)
+		assert.Equal(tool.NullVersion, from)
+		assert.Equal(tool.Version("v1.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), t1.UpstreamVersion())
+		assert.Equal(tool.Version("v1.0.0"), t1.CurrentVersion())
+	})
+	t.Run("NewUpstreamVersion", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest, latestErr = tool.Version("v2.0.0"), nil
+
+		// Pretend the previous update happened a while back
+		backdateCheckouts(engine, "github.com/stoic-cli/stoic-cli-core", time.Hour)
+
+		from, to, err := stoic.UpdateTool("test1", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.0.0"), from)
+		assert.Equal(tool.Version("v2.0.0"), to)
+
+		t1, err := engine.getTool("test1")
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), t1.CurrentVersion())
+	})
+	t.Run("UpstreamFailure", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest, latestErr = tool.NullVersion, errors.New("upstream is down")
+
+		from, to, err := stoic.UpdateTool("test1", false)
+		assert.NotNil(err)
+		assert.Equal(tool.Version("v2.0.0"), from)
+		assert.Equal(tool.NullVersion, to)
+	})
+	t.Run("PinnedVersion", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+		latest, latestErr = tool.Version("v2.0.0"), nil
+
+		from, to, err := stoic.UpdateTool("pinned", false)
+		assert.Nil(err)
+		assert.Equal(tool.Version("v0.1.0"), from)
+		assert.Equal(tool.Version("v0.1.0"), to)
+	})
+	t.Run("UnknownTool", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, _, err := stoic.UpdateTool("unknown", false)
+		assert.NotNil(err)
+	})
+}
+
+func backdateCheckouts(e *engine, toolId string, d time.Duration) {
+	ts := e.LoadState(toolId).(*toolState)
+	ts.updateInTransaction(func(UnixTimestamp) {
+		for i := range ts.Checkouts {
+			ts.Checkouts[i].Created -= UnixTimestamp(d.Seconds())
+			if ts.Checkouts[i].SetCurrent != 0 {
+				ts.Checkouts[i].SetCurrent -= UnixTimestamp(d.Seconds())
+			}
+		}
+	})
+}
+
+type latestVersionToolGetter struct {
+	mockToolGetter
+
+	latest tool.Version
+}
+
+func (g *latestVersionToolGetter) LatestVersion() (tool.Version, error) {
+	return g.latest, nil
+}
+
+func TestUpdateToolDryRunLooksUpLatestVersion(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	getter := &latestVersionToolGetter{latest: "v1.0.0"}
+	var fetchLatestCount int
+	getter.FetchLatestFunc = func() (tool.Version, error) {
+		fetchLatestCount++
+		return getter.latest, nil
+	}
+
+	RegisterGetter(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Getter, error) {
+		return getter, nil
+	})
+	RegisterRunner(t.Name(), func(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+		return &mockToolRunner{}, nil
+	})
+
+	err := ioutil.WriteFile("config", []byte(fmt.Sprintf(`
+tools:
+  test1:
+    endpoint: github.com/stoic-cli/test1
+    getter: '%[1]v'
+    runner: '%[1]v'
+`, t.Name())), 0644)
+	if err != nil {
+		t.Fatalf("unable to create config file: %v", err)
+	}
+
+	stoic, err := NewWithOptions(EngineOptions{
+		Root: tid.TestDir(),
+	})
+	if err != nil {
+		t.Fatalf("unable to set up stoic instance: %v", err)
+	}
+
+	from, to, err := stoic.UpdateTool("test1", true)
+	assert.Nil(t, err)
+	assert.Equal(t, tool.NullVersion, from)
+	assert.Equal(t, tool.Version("v1.0.0"), to)
+	assert.Equal(t, 0, fetchLatestCount)
+
+	_, _, err = stoic.UpdateTool("test1", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, fetchLatestCount)
+}
@@ -6,5 +6,16 @@ import (
 
 type StoicConfig struct {
 	UpdateFrequency tool.UpdateFrequency  `yaml:"update,omitempty"`
+	Hosts           map[string]HostConfig `yaml:",omitempty"`
 	Tools           map[string]ToolConfig `yaml:",omitempty"`
 }
+
+// HostConfig holds settings for accessing a host, such as credentials used by
+// getters.
+type HostConfig struct {
+	Token string `yaml:",omitempty"`
+
+	// CredentialHelper is a command that prints a token for the host, which
+	// is passed as the last argument.
+	CredentialHelper string `yaml:"credential-helper,omitempty"`
+}
@@ -13,6 +13,7 @@ func TestStoicConfig(t *testing.T) {
 		Name            string
 		Config          []byte
 		UpdateFrequency tool.UpdateFrequency
+		Hosts           map[string]HostConfig
 		ToolNames       []string
 	}{
 		{
@@ -46,6 +47,17 @@ func TestStoicConfig(t *testing.T) {
 			Config:          []byte(`update: never`),
 			UpdateFrequency: tool.UpdateNever,
 		},
+		{
+			Name: "Hosts",
+			Config: []byte(`
+hosts:
+  github.com: {token: secret}
+  github.example.com: {credential-helper: get-token --github}`),
+			Hosts: map[string]HostConfig{
+				"github.com":         {Token: "secret"},
+				"github.example.com": {CredentialHelper: "get-token --github"},
+			},
+		},
 		{
 			Name:            "FullyDefined",
 			Config:          []byte("update: daily\ntools: {beetle: {}, walrus: {}}\n"),
@@ -64,6 +76,7 @@ func TestStoicConfig(t *testing.T) {
 			assert.Nil(err)
 
 			assert.Equal(test.UpdateFrequency, config.UpdateFrequency)
+			assert.Equal(test.Hosts, config.Hosts)
 
 			assert.Len(config.Tools, len(test.ToolNames))
 			for _, name := range test.ToolNames {
@@ -9,10 +9,11 @@ import (
 type ToolConfig struct {
 	Endpoint        string
 	Channel         tool.Channel
-	UpdateFrequency tool.UpdateFrequency `yaml:"update,omitempty"`
-	PinVersion      tool.Version         `yaml:"pin-version,omitempty"`
-	Getter          ToolGetterConfig     `yaml:"getter,omitempty"`
-	Runner          ToolRunnerConfig     `yaml:"runner,omitempty"`
+	UpdateFrequency tool.UpdateFrequency   `yaml:"update,omitempty"`
+	PinVersion      tool.Version           `yaml:"pin-version,omitempty"`
+	Version         tool.VersionConstraint `yaml:"version,omitempty"`
+	Getter          ToolGetterConfig       `yaml:"getter,omitempty"`
+	Runner          ToolRunnerConfig       `yaml:"runner,omitempty"`
 }
 
 type ToolGetterConfig TypedOptions
@@ -27,10 +28,11 @@ func (tc *ToolConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var data struct {
 		Endpoint        string
 		Channel         tool.Channel
-		UpdateFrequency tool.UpdateFrequency `yaml:"update,omitempty"`
-		PinVersion      tool.Version         `yaml:"pin-version,omitempty"`
-		Getter          interface{}          `yaml:"getter,omitempty"`
-		Runner          interface{}          `yaml:"runner,omitempty"`
+		UpdateFrequency tool.UpdateFrequency   `yaml:"update,omitempty"`
+		PinVersion      tool.Version           `yaml:"pin-version,omitempty"`
+		Version         tool.VersionConstraint `yaml:"version,omitempty"`
+		Getter          interface{}            `yaml:"getter,omitempty"`
+		Runner          interface{}            `yaml:"runner,omitempty"`
 	}
 
 	if err := unmarshal(&data); err != nil {
@@ -41,6 +43,11 @@ func (tc *ToolConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	tc.Channel = data.Channel
 	tc.UpdateFrequency = data.UpdateFrequency
 	tc.PinVersion = data.PinVersion
+	tc.Version = data.Version
+
+	if err := tc.Version.Validate(); err != nil {
+		return err
+	}
 
 	var to TypedOptions
 
@@ -16,6 +16,7 @@ func TestToolConfig(t *testing.T) {
 		Channel         tool.Channel
 		UpdateFrequency tool.UpdateFrequency
 		PinVersion      tool.Version
+		Version         tool.VersionConstraint
 		GetterType      string
 		GetterOptions   map[string]interface{}
 		RunnerType      string
@@ -83,6 +84,11 @@ runner: {type: gallop, runner-option: value, another-option: another-value}`),
 			Config:     []byte(`runner: cheetah`),
 			RunnerType: "cheetah",
 		},
+		{
+			Name:    "VersionConstraint",
+			Config:  []byte(`version: ">=2.0, <3"`),
+			Version: tool.VersionConstraint(">=2.0, <3"),
+		},
 		{
 			Name:            "UpdateDaily",
 			Config:          []byte(`update: daily`),
@@ -123,6 +129,7 @@ runner: {type: gallop, runner-option: value, another-option: another-value}`),
 			assert.Equal(test.Channel, config.Channel)
 			assert.Equal(test.UpdateFrequency, config.UpdateFrequency)
 			assert.Equal(test.PinVersion, config.PinVersion)
+			assert.Equal(test.Version, config.Version)
 
 			assert.Equal(test.GetterType, config.Getter.Type)
 			assert.Len(config.Getter.Options, len(test.GetterOptions))
@@ -141,3 +148,11 @@ runner: {type: gallop, runner-option: value, another-option: another-value}`),
 		})
 	}
 }
+
+func TestToolConfigInvalidVersionConstraint(t *testing.T) {
+	assert := assert.New(t)
+
+	var config ToolConfig
+	err := yaml.Unmarshal([]byte(`version: latest`), &config)
+	assert.NotNil(err)
+}
@@ -0,0 +1,350 @@
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/shlex"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	gitformatconfig "gopkg.in/src-d/go-git.v4/plumbing/format/config"
+	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// tokenUsername is the user name sent along with tokens over HTTPS, when the
+// URL doesn't specify one.
+const tokenUsername = "x-access-token"
+
+// defaultSSHKeys are tried, in order, when no SSH key is configured and no SSH
+// agent is available.
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// authMethod returns the credentials the builtin client uses to fetch from
+// upstream, or nil if none are needed or available.
+func (gg Getter) authMethod() (gittransport.AuthMethod, error) {
+	switch gg.URL.Scheme {
+	case "ssh":
+		return gg.sshAuth()
+	case "http", "https":
+		return gg.httpAuth()
+	}
+	return nil, nil
+}
+
+// sshAuth authenticates with the configured SSH key or, failing that, with
+// the SSH agent or one of the default keys in ~/.ssh.
+func (gg Getter) sshAuth() (gittransport.AuthMethod, error) {
+	user := gitssh.DefaultUsername
+	if gg.URL.User != nil && gg.URL.User.Username() != "" {
+		user = gg.URL.User.Username()
+	}
+
+	if gg.SSHKey != "" {
+		keyPath, err := homedir.Expand(gg.SSHKey)
+		if err != nil {
+			return nil, err
+		}
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyPath, "")
+		return auth, errors.Wrapf(err, "unable to load SSH key, '%v'", gg.SSHKey)
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") != "" {
+		return gitssh.NewSSHAgentAuth(user)
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range defaultSSHKeys {
+		keyPath := filepath.Join(home, ".ssh", key)
+		if _, err := os.Stat(keyPath); err != nil {
+			continue
+		}
+
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyPath, "")
+		if err != nil {
+			jww.DEBUG.Printf("skipping SSH key %v: %v", keyPath, err)
+			continue
+		}
+		return auth, nil
+	}
+
+	return nil, errors.Errorf(
+		"no SSH agent or usable SSH key available to fetch from %v", gg.URL.Host)
+}
+
+// httpAuth looks up credentials for HTTP(S) remotes, in order, in the URL, in
+// the host configuration of stoic, from git credential helpers and in the
+// netrc file.
+func (gg Getter) httpAuth() (gittransport.AuthMethod, error) {
+	var user string
+	if gg.URL.User != nil {
+		// Credentials in the URL are used as is
+		if _, ok := gg.URL.User.Password(); ok {
+			return nil, nil
+		}
+		user = gg.URL.User.Username()
+	}
+
+	host := strings.ToLower(gg.URL.Hostname())
+
+	token := gg.hostConfig.Token
+	if token == "" && gg.hostConfig.CredentialHelper != "" {
+		var err error
+		token, err = util.RunCredentialHelper(gg.hostConfig.CredentialHelper, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if token != "" {
+		if user == "" {
+			user = tokenUsername
+		}
+		return &githttp.BasicAuth{Username: user, Password: token}, nil
+	}
+
+	if auth := gitCredentials(gg.URL, user); auth != nil {
+		return auth, nil
+	}
+	if auth := netrcCredentials(host, user); auth != nil {
+		return auth, nil
+	}
+	return nil, nil
+}
+
+// gitConfigFiles returns the paths of the user's git configuration files,
+// in order of increasing precedence.
+func gitConfigFiles() []string {
+	var files []string
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := homedir.Dir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		files = append(files, filepath.Join(configHome, "git", "config"))
+	}
+
+	if home, err := homedir.Dir(); err == nil {
+		files = append(files, filepath.Join(home, ".gitconfig"))
+	}
+	return files
+}
+
+// credentialHelpers returns the credential helpers that git would use for
+// u, as configured in the credential section and in credential.<url>
+// subsections of the user's git configuration. As with git, an empty helper
+// resets the list of helpers.
+func credentialHelpers(u *url.URL) []string {
+	var helpers []string
+
+	for _, file := range gitConfigFiles() {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+
+		config := gitformatconfig.New()
+		err = gitformatconfig.NewDecoder(f).Decode(config)
+		f.Close()
+		if err != nil {
+			jww.WARN.Printf("unable to read git configuration from %v: %v", file, err)
+			continue
+		}
+
+		section := config.Section("credential")
+
+		values := section.Options.GetAll("helper")
+		for _, subsection := range section.Subsections {
+			if credentialURLMatches(subsection.Name, u) {
+				values = append(values, subsection.Options.GetAll("helper")...)
+			}
+		}
+
+		for _, value := range values {
+			if value == "" {
+				helpers = nil
+				continue
+			}
+			helpers = append(helpers, value)
+		}
+	}
+	return helpers
+}
+
+// credentialURLMatches is true if the URL of a credential.<url> subsection
+// applies to u.
+func credentialURLMatches(pattern string, u *url.URL) bool {
+	p, err := url.Parse(pattern)
+	if err != nil || p.Host == "" {
+		return false
+	}
+	return strings.EqualFold(p.Scheme, u.Scheme) &&
+		strings.EqualFold(p.Host, u.Host) &&
+		strings.HasPrefix(u.Path, strings.TrimSuffix(p.Path, "/"))
+}
+
+// gitCredentials queries git credential helpers for credentials for u,
+// returning the first ones found.
+func gitCredentials(u *url.URL, user string) gittransport.AuthMethod {
+	request := "protocol=" + u.Scheme + "\nhost=" + u.Host + "\n" +
+		"path=" + strings.TrimPrefix(u.Path, "/") + "\n"
+	if user != "" {
+		request += "username=" + user + "\n"
+	}
+
+	for _, helper := range credentialHelpers(u) {
+		cmd, err := credentialHelperCommand(helper)
+		if err != nil {
+			jww.WARN.Printf("skipping git credential helper '%v': %v", helper, err)
+			continue
+		}
+
+		var stdout bytes.Buffer
+		cmd.Stdin = strings.NewReader(request + "\n")
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			jww.DEBUG.Printf("git credential helper '%v' failed: %v", helper, err)
+			continue
+		}
+
+		username, password := user, ""
+		scanner := bufio.NewScanner(&stdout)
+		for scanner.Scan() {
+			kv := strings.SplitN(scanner.Text(), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "username":
+				username = kv[1]
+			case "password":
+				password = kv[1]
+			}
+		}
+
+		if password != "" {
+			return &githttp.BasicAuth{Username: username, Password: password}
+		}
+	}
+	return nil
+}
+
+// credentialHelperCommand returns the command for a git credential helper,
+// as git would run it to get credentials.
+func credentialHelperCommand(helper string) (*exec.Cmd, error) {
+	if strings.HasPrefix(helper, "!") {
+		return exec.Command("/bin/sh", "-c", helper[1:]+" get"), nil
+	}
+
+	args, err := shlex.Split(helper)
+	if err != nil || len(args) == 0 {
+		return nil, errors.Errorf("invalid credential helper, '%v'", helper)
+	}
+	if !filepath.IsAbs(args[0]) {
+		args[0] = "git-credential-" + args[0]
+	}
+	return exec.Command(args[0], append(args[1:], "get")...), nil
+}
+
+// netrcCredentials returns credentials for host from the netrc file, named
+// by the NETRC environment variable or ~/.netrc by default.
+func netrcCredentials(host, user string) gittransport.AuthMethod {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	login, password, ok := parseNetrc(string(content), host, user)
+	if !ok {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: login, Password: password}
+}
+
+// parseNetrc returns the login and password for host in a netrc file. Entries
+// for other users are skipped if user is set. The default entry applies to
+// hosts without an entry of their own.
+func parseNetrc(content, host, user string) (string, string, bool) {
+	type entry struct {
+		machine, login, password string
+		isDefault                bool
+	}
+
+	var entries []entry
+	var current *entry
+
+	fields := strings.Fields(content)
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch fields[i] {
+		case "machine":
+			entries = append(entries, entry{machine: next()})
+			current = &entries[len(entries)-1]
+		case "default":
+			entries = append(entries, entry{isDefault: true})
+			current = &entries[len(entries)-1]
+		case "login":
+			if login := next(); current != nil {
+				current.login = login
+			}
+		case "password":
+			if password := next(); current != nil {
+				current.password = password
+			}
+		case "account":
+			next()
+		case "macdef":
+			// Macro definitions run until an empty line, which can't be
+			// told apart once split into fields. Stop parsing instead.
+			i = len(fields)
+		}
+	}
+
+	for _, isDefault := range []bool{false, true} {
+		for _, e := range entries {
+			if e.isDefault != isDefault || e.password == "" {
+				continue
+			}
+			if !isDefault && !strings.EqualFold(e.machine, host) {
+				continue
+			}
+			if user != "" && e.login != "" && e.login != user {
+				continue
+			}
+			return e.login, e.password, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,122 @@
+package getter
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	gitstorer "gopkg.in/src-d/go-git.v4/plumbing/storer"
+	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	gitclient "gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	gitserver "gopkg.in/src-d/go-git.v4/plumbing/transport/server"
+	gitfilesystem "gopkg.in/src-d/go-git.v4/storage/filesystem"
+)
+
+// Clients used by the git getter to fetch from upstream.
+const (
+	// NativeClient runs the git command, which handles authentication as
+	// configured for git.
+	NativeClient = "native"
+
+	// BuiltinClient fetches in-process, without requiring git to be
+	// installed.
+	BuiltinClient = "builtin"
+)
+
+// remoteName is the name of the remote the builtin client fetches from.
+const remoteName = "origin"
+
+func init() {
+	// The default file transport runs git-upload-pack, serve local
+	// repositories in-process instead
+	gitclient.InstallProtocol("file", gitserver.NewClient(localLoader{}))
+}
+
+// localLoader loads local repositories, bare or not, for the file transport.
+type localLoader struct{}
+
+func (localLoader) Load(ep *gittransport.Endpoint) (gitstorer.Storer, error) {
+	for _, dir := range []string{ep.Path, filepath.Join(ep.Path, ".git")} {
+		if _, err := os.Stat(filepath.Join(dir, "config")); err == nil {
+			return gitfilesystem.NewStorage(osfs.New(dir))
+		}
+	}
+	return nil, gittransport.ErrRepositoryNotFound
+}
+
+// usesNativeClient is true if fetching should be done by the git command. By
+// default, the git command is used if it is available.
+func (gg Getter) usesNativeClient() bool {
+	switch gg.Client {
+	case NativeClient:
+		return true
+	case BuiltinClient:
+		return false
+	}
+
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// fetchBuiltin fetches refspec from upstream into repo, without relying on
+// the git command.
+func (gg Getter) fetchBuiltin(repo *git.Repository, refspec string) error {
+	remote, err := gg.setupRemote(repo)
+	if err != nil {
+		return err
+	}
+
+	auth, err := gg.authMethod()
+	if err != nil {
+		return err
+	}
+
+	err = remote.Fetch(&git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(refspec)},
+		Auth:       auth,
+		Depth:      gg.Depth,
+		Tags:       git.NoTags,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return errors.Wrapf(err, "unable to fetch from %v", redactURL(gg.URL))
+}
+
+// redactURL returns u as a string, without any password.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	return redacted.String()
+}
+
+// setupRemote returns the remote for upstream, creating or updating it as
+// needed.
+func (gg Getter) setupRemote(repo *git.Repository) (*git.Remote, error) {
+	remoteURL := gg.URL.String()
+
+	remote, err := repo.Remote(remoteName)
+	if err == nil {
+		urls := remote.Config().URLs
+		if len(urls) == 1 && urls[0] == remoteURL {
+			return remote, nil
+		}
+		err = repo.DeleteRemote(remoteName)
+	}
+	if err != nil && err != git.ErrRemoteNotFound {
+		return nil, err
+	}
+
+	return repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{remoteURL},
+	})
+}
@@ -1,23 +1,23 @@
 package getter
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/format"
 	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
 	"gopkg.in/src-d/go-git.v4"
 	gitplumbing "gopkg.in/src-d/go-git.v4/plumbing"
-	gitobject "gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func NewGetter(stoic stoic.Stoic, tool stoic.Tool) (tool.Getter, error) {
@@ -34,28 +34,126 @@ func NewGetter(stoic stoic.Stoic, tool stoic.Tool) (tool.Getter, error) {
 		options.Branch = Branch(tool.Channel())
 	}
 
+	return newGetter(stoic, options, tool.Config().Version)
+}
+
+// newGetter validates options and returns a getter for the repository at
+// options.URL. Versions must satisfy constraint, if set.
+func newGetter(s stoic.Stoic, options Options, constraint tool.VersionConstraint) (*Getter, error) {
 	if !options.Branch.IsValid() {
 		return nil, errors.Errorf("invalid branch name, '%v'", options.Branch)
 	}
 
-	pathElems := []string{stoic.Root(), "git", options.URL.Hostname()}
+	switch options.Mode {
+	case "", BranchMode, TagsMode:
+	default:
+		return nil, errors.Errorf("invalid git getter mode, '%v'", options.Mode)
+	}
+
+	switch options.Client {
+	case "", NativeClient, BuiltinClient:
+	default:
+		return nil, errors.Errorf("invalid git client, '%v'", options.Client)
+	}
+
+	if options.Depth < 0 {
+		return nil, errors.Errorf("invalid fetch depth, %v", options.Depth)
+	}
+	if options.Filter != "" && options.Client == BuiltinClient {
+		return nil, errors.New("partial fetches are not supported by the builtin git client")
+	}
+
+	var tagPattern *regexp.Regexp
+	if options.TagPattern != "" {
+		var err error
+		tagPattern, err = regexp.Compile(options.TagPattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tag pattern, '%v'", options.TagPattern)
+		}
+	}
+
+	pathElems := []string{s.Root(), "git", options.URL.Hostname()}
 	pathElems = append(pathElems, strings.Split(options.URL.EscapedPath(), "/")...)
 	gitDir := filepath.Join(pathElems...)
 
-	return &Getter{options, gitDir}, nil
+	return &Getter{
+		Options:    options,
+		stoic:      s,
+		gitDir:     gitDir,
+		hostConfig: s.HostConfig(strings.ToLower(options.URL.Hostname())),
+		constraint: constraint,
+		tagPattern: tagPattern,
+	}, nil
 }
 
+// Modes of the git getter.
+const (
+	BranchMode = "branch"
+	TagsMode   = "tags"
+)
+
 type Options struct {
 	URL    *url.URL
 	Branch Branch
+
+	// Mode is either BranchMode, the default, or TagsMode.
+	Mode string
+
+	// TagPattern is a regular expression that restricts the tags considered
+	// in TagsMode. If it has a capturing group, the first one extracts the
+	// version that tags are compared by (e.g., ^release-(.*)$). Version
+	// constraints still apply to tag names.
+	TagPattern string `mapstructure:"tag-pattern"`
+
+	// Client is either NativeClient or BuiltinClient. By default, the native
+	// client is used if the git command is available.
+	Client string
+
+	// SSHKey is the path to a private key used by the builtin client for SSH
+	// remotes. By default, the SSH agent or a default key is used.
+	SSHKey string `mapstructure:"ssh-key"`
+
+	// Depth limits fetches to the specified number of commits from the tips
+	// fetched. Pinned versions that are older are fetched on demand.
+	Depth int
+
+	// Filter is passed on to git fetch for partial fetches (e.g.,
+	// blob:none). Objects left out are fetched on demand by the git command,
+	// which partial fetches require.
+	Filter string
+
+	// Submodules checks out submodules at the commits recorded, fetching
+	// them into repositories of their own, as with the main repository.
+	Submodules bool
+
+	// LFS replaces Git LFS pointers in checkouts with the objects they point
+	// to, which are fetched from the LFS server of the repository and cached.
+	LFS bool
 }
 
+// Getter follows the tip of a branch, using commit hashes as versions. In
+// TagsMode, or with a version constraint, it follows tags instead, using the
+// name of the highest tag as the version.
 type Getter struct {
 	Options
-	gitDir string
+	stoic      stoic.Stoic
+	gitDir     string
+	hostConfig format.HostConfig
+	constraint tool.VersionConstraint
+	tagPattern *regexp.Regexp
+}
+
+func (gg Getter) tracksTags() bool {
+	return gg.Mode == TagsMode || gg.constraint.IsSet()
 }
 
 func (gg Getter) runNativeGit(command string, args ...string) error {
+	return runNativeGitIn(gg.gitDir, "", command, args...)
+}
+
+// runNativeGitIn runs a git command on the repository in gitDir, with the
+// worktree in workTree, if not empty.
+func runNativeGitIn(gitDir, workTree, command string, args ...string) error {
 	var environment []string
 	for _, envVar := range os.Environ() {
 		switch strings.Split(envVar, "=")[0] {
@@ -70,12 +168,16 @@ func (gg Getter) runNativeGit(command string, args ...string) error {
 			environment = append(environment, envVar)
 		}
 	}
-	environment = append(environment, "GIT_DIR="+gg.gitDir)
+	environment = append(environment, "GIT_DIR="+gitDir)
 
 	cmd := exec.Command("git", command)
 	cmd.Args = append(cmd.Args, args...)
 
 	cmd.Env = environment
+	if workTree != "" {
+		cmd.Env = append(cmd.Env, "GIT_WORK_TREE="+workTree)
+		cmd.Dir = workTree
+	}
 
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
@@ -83,6 +185,23 @@ func (gg Getter) runNativeGit(command string, args ...string) error {
 	return cmd.Run()
 }
 
+// runNativeFetch fetches refspec from upstream with the git command.
+func (gg Getter) runNativeFetch(refspec string) error {
+	args := append([]string{"--quiet"}, gg.nativeFetchArgs()...)
+
+	if gg.Filter != "" {
+		// Partial fetches require a promisor remote
+		if err := gg.setupPromisorRemote(gg.gitDir); err != nil {
+			return err
+		}
+		return gg.runNativeGit("fetch", append(args, remoteName, refspec)...)
+	}
+
+	// invoke native git for the authentication
+	url, _ := gg.URL.MarshalBinary()
+	return gg.runNativeGit("fetch", append(args, string(url), refspec)...)
+}
+
 func (gg Getter) remoteReference() string {
 	if gg.Branch == "" {
 		return "HEAD"
@@ -98,9 +217,9 @@ func (gg Getter) localReference() string {
 }
 
 func (gg Getter) fetch() (gitplumbing.Revision, error) {
-	_, err := git.PlainOpen(gg.gitDir)
+	repo, err := git.PlainOpen(gg.gitDir)
 	if err == git.ErrRepositoryNotExists {
-		_, err = git.PlainInit(gg.gitDir, true)
+		repo, err = git.PlainInit(gg.gitDir, true)
 	}
 	if err != nil {
 		return "", err
@@ -108,10 +227,17 @@ func (gg Getter) fetch() (gitplumbing.Revision, error) {
 
 	localRef := gg.localReference()
 	refspec := fmt.Sprintf("+%v:%v", gg.remoteReference(), localRef)
+	if gg.tracksTags() {
+		refspec = tagsRefspec
+	}
 
-	// invoke native git for the authentication
-	url, _ := gg.URL.MarshalBinary()
-	err = gg.runNativeGit("fetch", "--quiet", string(url), refspec)
+	if gg.usesNativeClient() {
+		err = gg.runNativeFetch(refspec)
+	} else if gg.Filter != "" {
+		err = errors.New("partial fetches require the git command")
+	} else {
+		err = gg.fetchBuiltin(repo, refspec)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -119,7 +245,19 @@ func (gg Getter) fetch() (gitplumbing.Revision, error) {
 	return gitplumbing.Revision(localRef), nil
 }
 
+// lockGitDir acquires a lock serializing changes to the repository of the
+// getter, which is shared by tools with the same URL across stoic processes.
+func (gg Getter) lockGitDir() (util.FileLock, error) {
+	return util.LockSharedResource(gg.gitDir)
+}
+
 func (gg Getter) FetchLatest() (tool.Version, error) {
+	gitDirLock, err := gg.lockGitDir()
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	defer gitDirLock.Unlock()
+
 	ref, err := gg.fetch()
 	if err != nil {
 		return tool.NullVersion, err
@@ -128,6 +266,11 @@ func (gg Getter) FetchLatest() (tool.Version, error) {
 	if err != nil {
 		return tool.NullVersion, err
 	}
+
+	if gg.tracksTags() {
+		return gg.latestTag(repo)
+	}
+
 	version, err := repo.ResolveRevision(ref)
 	if err != nil {
 		return tool.NullVersion, err
@@ -136,57 +279,94 @@ func (gg Getter) FetchLatest() (tool.Version, error) {
 }
 
 func (gg Getter) FetchVersion(pinVersion tool.Version) error {
+	gitDirLock, err := gg.lockGitDir()
+	if err != nil {
+		return err
+	}
+	defer gitDirLock.Unlock()
+
 	localRef, err := gg.fetch()
 	if err != nil {
 		return err
 	}
 
-	pinHash := gitplumbing.NewHash(string(pinVersion))
 	repo, err := git.PlainOpen(gg.gitDir)
 	if err != nil {
 		return err
 	}
-	pinCommit, err := repo.CommitObject(pinHash)
-	if err != nil {
+
+	if gg.tracksTags() {
+		_, err := resolveTag(repo, pinVersion)
 		return err
 	}
+
+	pinHash := gitplumbing.NewHash(string(pinVersion))
 	tipHash, err := repo.ResolveRevision(localRef)
 	if err != nil {
 		return err
 	}
-	tipCommit, err := repo.CommitObject(*tipHash)
-	if err != nil {
+
+	reachable, incomplete, err := reachableFrom(repo, *tipHash, pinHash)
+	if err != nil || reachable {
 		return err
 	}
+	if incomplete && gg.isShallow() {
+		return gg.fetchCommit(repo, pinHash)
+	}
+	return errors.Errorf(
+		"requested version, %.12v, is unreachable from %v branch",
+		pinHash.String(), gg.Branch)
+}
 
-	// Ensure commit is reachable from branch?
-	cIter := gitobject.NewCommitPostorderIter(tipCommit, nil)
-	defer cIter.Close()
+func (gg Getter) CheckoutTo(version tool.Version, path string) error {
+	err := gg.checkoutWorktree(version, path)
+	if err != nil {
+		return err
+	}
 
-	for {
-		commit, err := cIter.Next()
-		switch err {
-		case nil:
-			if bytes.Equal(pinCommit.Hash[:], commit.Hash[:]) {
-				return nil
-			}
+	// Submodules are checked out without holding the lock on the repository,
+	// as they may share it with the superproject (e.g., url = ./).
+	if gg.Submodules {
+		err = gg.checkoutSubmodules(path)
+		if err != nil {
+			return err
+		}
+	}
+	if gg.LFS {
+		err = gg.checkoutLFSObjects(path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		case io.EOF:
-			return errors.Errorf(
-				"requested version, %.12v, is unreachable from %v branch",
-				pinCommit.Hash.String(), gg.Branch)
+// checkoutWorktree checks out version into path, sharing objects with the
+// repository of the getter.
+func (gg Getter) checkoutWorktree(version tool.Version, path string) error {
+	gitDirLock, err := gg.lockGitDir()
+	if err != nil {
+		return err
+	}
+	defer gitDirLock.Unlock()
 
-		default:
+	versionHash := gitplumbing.NewHash(string(version))
+	if gg.tracksTags() {
+		srcRepo, err := git.PlainOpen(gg.gitDir)
+		if err != nil {
 			return err
 		}
+		versionHash, err = resolveTag(srcRepo, version)
+		if err != nil {
+			return errors.Wrapf(tool.ErrVersionNotFetched,
+				"tag '%v' not found in %v", version, gg.gitDir)
+		}
 	}
-}
 
-func (gg Getter) CheckoutTo(version tool.Version, path string) error {
 	dstGitDir := filepath.Join(path, ".git")
 
 	dstHeads := filepath.Join(dstGitDir, "refs", "heads")
-	err := os.MkdirAll(dstHeads, 0777)
+	err = os.MkdirAll(dstHeads, 0777)
 	if err != nil {
 		return err
 	}
@@ -197,7 +377,7 @@ func (gg Getter) CheckoutTo(version tool.Version, path string) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(filepath.Join(dstHeads, "master"), []byte(version), 0644)
+	err = ioutil.WriteFile(filepath.Join(dstHeads, "master"), []byte(versionHash.String()), 0644)
 	if err != nil {
 		return err
 	}
@@ -220,6 +400,15 @@ func (gg Getter) CheckoutTo(version tool.Version, path string) error {
 		return err
 	}
 
+	// Objects are shared, and so are the boundaries of shallow history
+	srcShallow := filepath.Join(gg.gitDir, "shallow")
+	if _, err := os.Stat(srcShallow); err == nil {
+		err = os.Symlink(srcShallow, filepath.Join(dstGitDir, "shallow"))
+		if err != nil {
+			return err
+		}
+	}
+
 	config, err := os.Create(filepath.Join(dstGitDir, "config"))
 	if err != nil {
 		return err
@@ -233,6 +422,20 @@ remote = origin
 `, gg.URL, "master")
 	config.Close()
 
+	if gg.Filter != "" {
+		err = gg.nativeCheckout(path, versionHash)
+	} else {
+		err = resetWorktree(path, versionHash)
+	}
+	if err == gitplumbing.ErrObjectNotFound {
+		return errors.Wrapf(tool.ErrVersionNotFetched,
+			"commit %.12v not found in %v", version, gg.gitDir)
+	}
+	return err
+}
+
+// resetWorktree updates the worktree at path to commit.
+func resetWorktree(path string, commit gitplumbing.Hash) error {
 	repo, err := git.PlainOpen(path)
 	if err != nil {
 		return err
@@ -242,14 +445,8 @@ remote = origin
 		return err
 	}
 
-	versionHash := gitplumbing.NewHash(string(version))
-	err = wt.Reset(&git.ResetOptions{
-		Commit: versionHash,
+	return wt.Reset(&git.ResetOptions{
+		Commit: commit,
 		Mode:   git.HardReset,
 	})
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
@@ -0,0 +1,509 @@
+package getter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCache map[string][]byte
+
+func (mc memoryCache) Get(key string) io.ReadCloser {
+	if data, ok := mc[key]; ok {
+		return ioutil.NopCloser(bytes.NewReader(data))
+	}
+	return nil
+}
+
+func (mc memoryCache) Put(key string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	mc[key] = data
+	return nil
+}
+
+type testStoic struct {
+	stoic.Stoic
+	root  string
+	cache memoryCache
+	hosts map[string]format.HostConfig
+}
+
+func (ts testStoic) Root() string       { return ts.root }
+func (ts testStoic) Cache() stoic.Cache { return ts.cache }
+
+func (ts testStoic) HostConfig(host string) format.HostConfig {
+	return ts.hosts[host]
+}
+
+type testTool struct {
+	stoic.Tool
+	endpoint *url.URL
+	config   format.ToolConfig
+}
+
+func newTestGetter(t *testing.T, root string, endpoint *url.URL, config format.ToolConfig) tool.Getter {
+	getter, err := NewGetter(testStoic{root: root, cache: memoryCache{}},
+		testTool{endpoint: endpoint, config: config})
+	if err != nil {
+		t.Fatalf("unable to create getter: %v", err)
+	}
+	return getter
+}
+
+func assertCheckout(t *testing.T, getter tool.Getter, version tool.Version, dir, expected string) {
+	assert := assert.New(t)
+
+	assert.Nil(os.Mkdir(dir, 0700))
+	assert.Nil(getter.CheckoutTo(version, dir))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "VERSION"))
+	assert.Nil(err)
+	assert.Equal(expected, string(content))
+}
+
+func (tt testTool) Endpoint() *url.URL        { return tt.endpoint }
+func (tt testTool) Config() format.ToolConfig { return tt.config }
+func (tt testTool) Channel() tool.Channel     { return tool.DefaultChannel }
+
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=Stoic", "-c", "user.email=stoic@example.com",
+		"-c", "init.defaultBranch=master",
+	}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+// makeUpstream creates a repository with a commit for each of versions,
+// tagged with the version. The VERSION file in each commit holds the version.
+func makeUpstream(t *testing.T, dir string, versions ...string) *url.URL {
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("unable to create upstream repository: %v", err)
+	}
+	runGit(t, dir, "init", "--quiet")
+
+	for _, version := range versions {
+		err := ioutil.WriteFile(filepath.Join(dir, "VERSION"), []byte(version), 0644)
+		if err != nil {
+			t.Fatalf("unable to write VERSION: %v", err)
+		}
+		runGit(t, dir, "add", "VERSION")
+		runGit(t, dir, "commit", "--quiet", "-m", "Release "+version)
+		runGit(t, dir, "tag", "-a", "-m", "Release "+version, version)
+	}
+
+	absDir, _ := filepath.Abs(dir)
+	return &url.URL{Scheme: "file", Path: filepath.ToSlash(absDir)}
+}
+
+func TestGetterVersionConstraint(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	upstream := makeUpstream(t, "upstream",
+		"v1.0.0", "v1.2.0", "v2.0.0", "v2.1.0-rc.1", "nightly")
+
+	newGetter := func(constraint tool.VersionConstraint) tool.Getter {
+		return newTestGetter(t, tid.TestDir(), upstream,
+			format.ToolConfig{Version: constraint})
+	}
+
+	t.Run("Highest", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+
+		getter := newGetter("^1")
+		version, err := getter.FetchLatest()
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.2.0"), version)
+
+		assertCheckout(t, getter, version, testDir, "v1.2.0")
+	})
+	t.Run("SkipsPrereleases", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		version, err := newGetter(">=1.0").FetchLatest()
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), version)
+	})
+	t.Run("NoMatch", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, err := newGetter("^3").FetchLatest()
+		assert.NotNil(err)
+	})
+	t.Run("FetchVersion", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+
+		getter := newGetter("^1")
+		assert.Nil(getter.FetchVersion("v1.0.0"))
+		assert.NotNil(getter.FetchVersion("v1.1.0"))
+
+		assertCheckout(t, getter, "v1.0.0", testDir, "v1.0.0")
+	})
+	t.Run("WithoutConstraint", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		version, err := newGetter(tool.NoConstraint).FetchLatest()
+		assert.Nil(err)
+		assert.Len(string(version), 40)
+	})
+}
+
+func TestGetterTagsMode(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	upstream := makeUpstream(t, "upstream",
+		"v1.0.0", "v1.10.0", "v1.9.0", "v2.0.0-rc.1", "nightly",
+		"release-9", "release-10", "tools/v3.0.0")
+
+	newGetter := func(options map[string]interface{}) tool.Getter {
+		options["mode"] = TagsMode
+
+		var config format.ToolConfig
+		config.Getter.Options = options
+		return newTestGetter(t, tid.TestDir(), upstream, config)
+	}
+
+	data := []struct {
+		Name       string
+		TagPattern string
+		Expected   tool.Version
+	}{
+		{"Semver", "", "v1.10.0"},
+		{"Pattern", `^v1\.[0-9]\.`, "v1.9.0"},
+		{"CapturingPattern", `^release-(.*)$`, "release-10"},
+		{"HierarchicalTag", `^tools/(.*)$`, "tools/v3.0.0"},
+		{"NonSemverPattern", `^nightly$`, "nightly"},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+
+			getter := newGetter(map[string]interface{}{"tag-pattern": test.TagPattern})
+
+			version, err := getter.FetchLatest()
+			assert.Nil(err)
+			assert.Equal(test.Expected, version)
+			assert.Nil(getter.FetchVersion(version))
+
+			assertCheckout(t, getter, version, testDir, string(test.Expected))
+		})
+	}
+
+	t.Run("NoMatch", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, err := newGetter(map[string]interface{}{"tag-pattern": "^v4"}).FetchLatest()
+		assert.NotNil(err)
+	})
+	t.Run("InvalidOptions", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		for _, options := range []map[string]interface{}{
+			{"mode": "revisions"},
+			{"mode": TagsMode, "tag-pattern": "v1.("},
+		} {
+			var config format.ToolConfig
+			config.Getter.Options = options
+
+			_, err := NewGetter(testStoic{root: tid.TestDir()},
+				testTool{endpoint: upstream, config: config})
+			assert.NotNil(err)
+		}
+	})
+}
+
+func TestGetterBuiltinClient(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	upstream := makeUpstream(t, "upstream", "v1.0.0", "v1.1.0")
+
+	// Without git in the PATH, the builtin client is used
+	defer util.SetenvForTest("PATH", "")()
+
+	newGetter := func(mode string) tool.Getter {
+		var config format.ToolConfig
+		config.Getter.Options = map[string]interface{}{"mode": mode}
+		return newTestGetter(t, tid.TestDir(), upstream, config)
+	}
+
+	t.Run("Branch", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+
+		getter := newGetter(BranchMode)
+		version, err := getter.FetchLatest()
+		assert.Nil(err)
+		assert.Len(string(version), 40)
+		assert.Nil(getter.FetchVersion(version))
+
+		assertCheckout(t, getter, version, testDir, "v1.1.0")
+	})
+	t.Run("Tags", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+
+		getter := newGetter(TagsMode)
+		version, err := getter.FetchLatest()
+		assert.Nil(err)
+		assert.Equal(tool.Version("v1.1.0"), version)
+		assert.Nil(getter.FetchVersion("v1.0.0"))
+
+		assertCheckout(t, getter, "v1.0.0", testDir, "v1.0.0")
+	})
+}
+
+func revParse(t *testing.T, dir, revision string) tool.Version {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", revision+"^{commit}").Output()
+	if err != nil {
+		t.Fatalf("unable to resolve %v: %v", revision, err)
+	}
+	return tool.Version(strings.TrimSpace(string(output)))
+}
+
+func TestGetterShallowFetch(t *testing.T) {
+	if _, err := exec.Command("git", "version").Output(); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	upstream := makeUpstream(t, "upstream", "v1.0.0", "v1.1.0", "v1.2.0")
+	runGit(t, "upstream", "config", "uploadpack.allowFilter", "true")
+
+	oldVersion := revParse(t, "upstream", "v1.0.0")
+
+	data := []struct {
+		Name    string
+		Options map[string]interface{}
+	}{
+		{"Depth", map[string]interface{}{"depth": 1}},
+		{"Filter", map[string]interface{}{"filter": "blob:none"}},
+		{"DepthAndFilter", map[string]interface{}{"depth": 1, "filter": "blob:none"}},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+
+			testDir, _ = filepath.Abs(testDir)
+
+			var config format.ToolConfig
+			config.Getter.Options = d.Options
+			getter := newTestGetter(t, testDir, upstream, config)
+
+			version, err := getter.FetchLatest()
+			assert.Nil(err)
+			assert.Equal(revParse(t, "upstream", "v1.2.0"), version)
+
+			gitDir := getter.(*Getter).gitDir
+			_, err = os.Stat(filepath.Join(gitDir, "shallow"))
+			assert.Equal(d.Options["depth"] != nil, err == nil)
+
+			// Older versions are fetched on demand
+			assert.Nil(getter.FetchVersion(oldVersion))
+
+			assertCheckout(t, getter, version, filepath.Join(testDir, "latest"), "v1.2.0")
+			assertCheckout(t, getter, oldVersion, filepath.Join(testDir, "old"), "v1.0.0")
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, testDir := tid.SetupTest(t)
+
+		for _, options := range []map[string]interface{}{
+			{"depth": -1},
+			{"client": BuiltinClient, "filter": "blob:none"},
+		} {
+			var config format.ToolConfig
+			config.Getter.Options = options
+			_, err := NewGetter(testStoic{root: testDir}, testTool{endpoint: upstream, config: config})
+			assert.NotNil(t, err, "%v", options)
+		}
+	})
+}
+
+func TestGetterSubmodules(t *testing.T) {
+	if _, err := exec.Command("git", "version").Output(); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	makeUpstream(t, "lib", "lib-v1", "lib-v2")
+
+	// The recorded commit is not part of the branch of the submodule
+	runGit(t, "lib", "checkout", "--quiet", "-b", "side", "lib-v1")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join("lib", "VERSION"), []byte("lib-side"), 0644))
+	runGit(t, "lib", "commit", "--quiet", "-am", "Side")
+	runGit(t, "lib", "checkout", "--quiet", "master")
+
+	upstream := makeUpstream(t, "super", "v1.0.0")
+	runGit(t, "super", "-c", "protocol.file.allow=always",
+		"submodule", "--quiet", "add", "../lib", "lib")
+	runGit(t, "super/lib", "checkout", "--quiet", "side")
+	runGit(t, "super", "add", "lib")
+	runGit(t, "super", "commit", "--quiet", "-m", "Add lib")
+
+	data := []struct {
+		Name       string
+		Submodules bool
+		Expected   string
+	}{
+		{"Disabled", false, ""},
+		{"Enabled", true, "lib-side"},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+			testDir, _ = filepath.Abs(testDir)
+
+			var config format.ToolConfig
+			config.Getter.Options = map[string]interface{}{"submodules": d.Submodules}
+			getter := newTestGetter(t, testDir, upstream, config)
+
+			version, err := getter.FetchLatest()
+			assert.Nil(err)
+
+			checkoutDir := filepath.Join(testDir, "checkout")
+			assertCheckout(t, getter, version, checkoutDir, "v1.0.0")
+
+			content, _ := ioutil.ReadFile(filepath.Join(checkoutDir, "lib", "VERSION"))
+			assert.Equal(d.Expected, string(content))
+		})
+	}
+}
+
+func TestGetterSubmoduleOfItself(t *testing.T) {
+	if _, err := exec.Command("git", "version").Output(); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	// The submodule shares the repository of the superproject
+	upstream := makeUpstream(t, "self", "v1.0.0")
+	runGit(t, "self", "-c", "protocol.file.allow=always",
+		"submodule", "--quiet", "add", upstream.String(), "docs")
+	runGit(t, "self", "config", "--file", ".gitmodules", "submodule.docs.url", "./")
+	runGit(t, "self", "add", ".gitmodules")
+	runGit(t, "self", "commit", "--quiet", "-m", "Add docs")
+
+	var config format.ToolConfig
+	config.Getter.Options = map[string]interface{}{"submodules": true}
+	getter := newTestGetter(t, tid.TestDir(), upstream, config)
+
+	version, err := getter.FetchLatest()
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assertCheckout(t, getter, version, filepath.Join(tid.TestDir(), "checkout"), "v1.0.0")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("checkout of submodule sharing the superproject's repository deadlocked")
+	}
+
+	content, _ := ioutil.ReadFile(filepath.Join(tid.TestDir(), "checkout", "docs", "VERSION"))
+	assert.Equal(t, "v1.0.0", string(content))
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	superproject, _ := url.Parse("https://github.com/stoic-cli/tool.git")
+
+	data := []struct {
+		Name      string
+		Submodule string
+		Expected  string
+	}{
+		{"Sibling", "../lib.git", "https://github.com/stoic-cli/lib.git"},
+		{"Nested", "./lib", "https://github.com/stoic-cli/tool.git/lib"},
+		{"Absolute", "https://example.com/lib.git", "https://example.com/lib.git"},
+		{"ScpLike", "git@example.com:team/lib.git", "ssh://git@example.com/team/lib.git"},
+		{"LocalPath", "/srv/git/lib", "file:///srv/git/lib"},
+		{"Invalid", "lib", ""},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			resolved, err := resolveSubmoduleURL(superproject, d.Submodule)
+			if d.Expected == "" {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, d.Expected, resolved.String())
+		})
+	}
+}
+
+func TestGetterWaitsForRepositoryLock(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	upstream := makeUpstream(t, "upstream", "v1.0.0")
+	getter := newTestGetter(t, tid.TestDir(), upstream, format.ToolConfig{})
+
+	// Another process is fetching into the repository
+	lock, err := util.LockSharedResource(getter.(*Getter).gitDir)
+	if err != nil {
+		t.Fatalf("unable to lock repository: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := getter.FetchLatest()
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("fetched while the repository was locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	lock.Unlock()
+	assert.Nil(t, <-done)
+}
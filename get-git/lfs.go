@@ -0,0 +1,388 @@
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	gitformatconfig "gopkg.in/src-d/go-git.v4/plumbing/format/config"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+const (
+	// lfsPointerVersion is the first line of Git LFS pointer files.
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+	// lfsMaxPointerSize is the size above which files are not considered to
+	// be LFS pointers, as with git-lfs.
+	lfsMaxPointerSize = 1024
+
+	lfsMediaType = "application/vnd.git-lfs+json"
+
+	// lfsBatchSize is the number of objects requested from the LFS server
+	// at once.
+	lfsBatchSize = 100
+)
+
+var lfsOidRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// lfsPointer identifies an LFS object by its SHA-256 digest and size.
+type lfsPointer struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// parseLFSPointer parses the content of an LFS pointer file. It returns false
+// if content is not a pointer.
+func parseLFSPointer(content []byte) (lfsPointer, bool) {
+	if len(content) > lfsMaxPointerSize ||
+		!bytes.HasPrefix(content, []byte(lfsPointerVersion+"\n")) {
+		return lfsPointer{}, false
+	}
+
+	var pointer lfsPointer
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), " ", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "oid":
+			pointer.Oid = strings.TrimPrefix(kv[1], "sha256:")
+		case "size":
+			pointer.Size, _ = strconv.ParseInt(kv[1], 10, 64)
+		}
+	}
+
+	if !lfsOidRegexp.MatchString(pointer.Oid) {
+		return lfsPointer{}, false
+	}
+	return pointer, true
+}
+
+func lfsCacheKey(oid string) string {
+	return "lfs/" + oid
+}
+
+// findLFSPointers returns the LFS pointer files in the worktree at path,
+// keyed by file path. Submodules are skipped, they are checked out with LFS
+// objects of their own.
+func findLFSPointers(path string) (map[string]lfsPointer, error) {
+	pointers := map[string]lfsPointer{}
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if file != path {
+				if _, err := os.Lstat(filepath.Join(file, ".git")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || info.Size() > lfsMaxPointerSize {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if pointer, ok := parseLFSPointer(content); ok {
+			pointers[file] = pointer
+		}
+		return nil
+	})
+	return pointers, err
+}
+
+// checkoutLFSObjects replaces the LFS pointers in the worktree at path with
+// the objects they point to. Objects are fetched into the cache as needed.
+func (gg Getter) checkoutLFSObjects(path string) error {
+	pointers, err := findLFSPointers(path)
+	if err != nil || len(pointers) == 0 {
+		return err
+	}
+
+	cache := gg.stoic.Cache()
+
+	var missing []lfsPointer
+	seen := map[string]bool{}
+	for _, pointer := range pointers {
+		if seen[pointer.Oid] {
+			continue
+		}
+		seen[pointer.Oid] = true
+
+		if r := cache.Get(lfsCacheKey(pointer.Oid)); r != nil {
+			r.Close()
+			continue
+		}
+		missing = append(missing, pointer)
+	}
+
+	if len(missing) != 0 {
+		endpoint, err := gg.lfsEndpoint(path)
+		if err != nil {
+			return err
+		}
+
+		jww.INFO.Printf("fetching %v LFS objects from %v", len(missing), redactURL(endpoint))
+		if endpoint.Scheme == "file" {
+			err = gg.copyLocalLFSObjects(endpoint, missing)
+		} else {
+			err = gg.downloadLFSObjects(endpoint, missing)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for file, pointer := range pointers {
+		if err := gg.replaceLFSPointer(file, pointer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceLFSPointer overwrites the pointer file with the cached object.
+func (gg Getter) replaceLFSPointer(file string, pointer lfsPointer) error {
+	r := gg.stoic.Cache().Get(lfsCacheKey(pointer.Oid))
+	if r == nil {
+		return errors.Errorf("LFS object %v for %v is not available", pointer.Oid, file)
+	}
+	defer r.Close()
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return errors.Wrapf(err, "unable to check out LFS object for %v", file)
+}
+
+// putLFSObject stores the object read from r in the cache, once verified.
+func (gg Getter) putLFSObject(pointer lfsPointer, r io.Reader) error {
+	err := gg.stoic.Cache().Put(lfsCacheKey(pointer.Oid), util.NewSha256Reader(r, pointer.Oid))
+	return errors.Wrapf(err, "unable to fetch LFS object %v", pointer.Oid)
+}
+
+// lfsEndpoint returns the URL of the LFS server, as configured in .lfsconfig
+// in the worktree at path, or derived from the URL of the repository as
+// git-lfs does. For local repositories, this is the repository itself.
+func (gg Getter) lfsEndpoint(path string) (*url.URL, error) {
+	if f, err := os.Open(filepath.Join(path, ".lfsconfig")); err == nil {
+		config := gitformatconfig.New()
+		err = gitformatconfig.NewDecoder(f).Decode(config)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read .lfsconfig")
+		}
+		if lfsURL := config.Section("lfs").Options.Get("url"); lfsURL != "" {
+			return url.Parse(lfsURL)
+		}
+	}
+
+	endpoint := *gg.URL
+	switch endpoint.Scheme {
+	case "file":
+		return &endpoint, nil
+
+	case "ssh", "git+ssh", "ssh+git":
+		endpoint.Scheme = "https"
+		endpoint.Host = endpoint.Hostname()
+		endpoint.User = nil
+
+	case "http", "https":
+
+	default:
+		return nil, errors.Errorf("unable to fetch LFS objects for %v", redactURL(gg.URL))
+	}
+
+	if !strings.HasSuffix(endpoint.Path, ".git") {
+		endpoint.Path += ".git"
+	}
+	endpoint.Path += "/info/lfs"
+	endpoint.RawPath = ""
+	return &endpoint, nil
+}
+
+// copyLocalLFSObjects copies objects from the LFS storage of a local
+// repository.
+func (gg Getter) copyLocalLFSObjects(endpoint *url.URL, pointers []lfsPointer) error {
+	repoPath := filepath.FromSlash(endpoint.Path)
+
+	for _, pointer := range pointers {
+		var f *os.File
+		var err error
+		for _, dir := range []string{filepath.Join(repoPath, ".git"), repoPath} {
+			f, err = os.Open(filepath.Join(dir, "lfs", "objects",
+				pointer.Oid[0:2], pointer.Oid[2:4], pointer.Oid))
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return errors.Errorf("LFS object %v not found in %v", pointer.Oid, repoPath)
+		}
+
+		err = gg.putLFSObject(pointer, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []lfsPointer `json:"objects"`
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfsPointer
+		Actions struct {
+			Download *lfsAction `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// downloadLFSObjects fetches objects from an LFS server with the batch API,
+// see https://github.com/git-lfs/git-lfs/blob/main/docs/api/batch.md.
+func (gg Getter) downloadLFSObjects(endpoint *url.URL, pointers []lfsPointer) error {
+	// Credentials are looked up for the LFS server, which .lfsconfig in the
+	// checkout may place on any host.
+	authGetter := gg
+	authGetter.URL = endpoint
+	authGetter.hostConfig = gg.stoic.HostConfig(strings.ToLower(endpoint.Hostname()))
+	auth, err := authGetter.httpAuth()
+	if err != nil {
+		return err
+	}
+
+	authenticate := func(req *http.Request) {
+		if basic, ok := auth.(*githttp.BasicAuth); ok && req.URL.Host == endpoint.Host {
+			req.SetBasicAuth(basic.Username, basic.Password)
+		}
+	}
+
+	for len(pointers) != 0 {
+		batch := pointers
+		if len(batch) > lfsBatchSize {
+			batch = batch[:lfsBatchSize]
+		}
+		pointers = pointers[len(batch):]
+
+		body, err := json.Marshal(lfsBatchRequest{
+			Operation: "download",
+			Transfers: []string{"basic"},
+			Objects:   batch,
+		})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest("POST", endpoint.String()+"/objects/batch", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", lfsMediaType)
+		req.Header.Set("Content-Type", lfsMediaType)
+		authenticate(req)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		var batchResponse lfsBatchResponse
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&batchResponse)
+		} else {
+			err = errors.Errorf("unexpected HTTP status from LFS server at %v: %v",
+				redactURL(endpoint), resp.Status)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, object := range batchResponse.Objects {
+			if object.Error != nil {
+				return errors.Errorf("unable to fetch LFS object %v: %v (%v)",
+					object.Oid, object.Error.Message, object.Error.Code)
+			}
+			if object.Actions.Download == nil {
+				return errors.Errorf("no download available for LFS object %v", object.Oid)
+			}
+
+			err = gg.downloadLFSObject(object.lfsPointer, object.Actions.Download, authenticate)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (gg Getter) downloadLFSObject(pointer lfsPointer, action *lfsAction, authenticate func(*http.Request)) error {
+	req, err := http.NewRequest("GET", action.Href, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	if req.Header.Get("Authorization") == "" {
+		authenticate(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected HTTP status while fetching LFS object %v: %v",
+			pointer.Oid, resp.Status)
+	}
+	return gg.putLFSObject(pointer, resp.Body)
+}
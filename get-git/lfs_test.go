@@ -0,0 +1,204 @@
+package getter
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const lfsContent = "large binary content"
+
+func lfsPointerFor(content string) (lfsPointer, string) {
+	digest := sha256.Sum256([]byte(content))
+	pointer := lfsPointer{Oid: hex.EncodeToString(digest[:]), Size: int64(len(content))}
+	return pointer, fmt.Sprintf("%v\noid sha256:%v\nsize %v\n",
+		lfsPointerVersion, pointer.Oid, pointer.Size)
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	pointer, pointerFile := lfsPointerFor(lfsContent)
+
+	data := []struct {
+		Name     string
+		Content  string
+		Expected bool
+	}{
+		{"Pointer", pointerFile, true},
+		{"Empty", "", false},
+		{"Content", lfsContent, false},
+		{"InvalidOid", lfsPointerVersion + "\noid sha256:1234\nsize 20\n", false},
+		{"TooLarge", pointerFile + strings.Repeat("x", lfsMaxPointerSize), false},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			parsed, ok := parseLFSPointer([]byte(d.Content))
+			assert.Equal(t, d.Expected, ok)
+			if ok {
+				assert.Equal(t, pointer, parsed)
+			}
+		})
+	}
+}
+
+// makeLFSUpstream creates a repository with an LFS pointer to lfsContent in
+// the asset file. The object is stored in the repository, as with git-lfs,
+// unless lfsURL is set, in which case it is configured in .lfsconfig.
+func makeLFSUpstream(t *testing.T, dir, lfsURL string) *url.URL {
+	upstream := makeUpstream(t, dir, "v1.0.0")
+
+	pointer, pointerFile := lfsPointerFor(lfsContent)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "asset"), []byte(pointerFile), 0644))
+	runGit(t, dir, "add", "asset")
+
+	if lfsURL == "" {
+		objectDir := filepath.Join(dir, ".git", "lfs", "objects", pointer.Oid[0:2], pointer.Oid[2:4])
+		assert.Nil(t, os.MkdirAll(objectDir, 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(objectDir, pointer.Oid), []byte(lfsContent), 0644))
+	} else {
+		lfsConfig := fmt.Sprintf("[lfs]\n\turl = %v\n", lfsURL)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, ".lfsconfig"), []byte(lfsConfig), 0644))
+		runGit(t, dir, "add", ".lfsconfig")
+	}
+	runGit(t, dir, "commit", "--quiet", "-m", "Add asset")
+	return upstream
+}
+
+func TestGetterLFS(t *testing.T) {
+	if _, err := exec.Command("git", "version").Output(); err != nil {
+		t.Skip("git command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var requests []string
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/lfs/objects/batch":
+			authorization = r.Header.Get("Authorization")
+
+			var batch lfsBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			var objects []interface{}
+			for _, object := range batch.Objects {
+				objects = append(objects, map[string]interface{}{
+					"oid":  object.Oid,
+					"size": object.Size,
+					"actions": map[string]interface{}{
+						"download": map[string]interface{}{
+							"href":   "http://" + r.Host + "/objects/" + object.Oid,
+							"header": map[string]string{"X-Test": "lfs"},
+						},
+					},
+				})
+			}
+			w.Header().Set("Content-Type", lfsMediaType)
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+
+		default:
+			if r.Header.Get("X-Test") != "lfs" {
+				http.Error(w, "missing header", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(lfsContent))
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("unable to parse server URL: %v", err)
+	}
+	serverRequests := []string{"POST /lfs/objects/batch", "GET /objects/"}
+
+	data := []struct {
+		Name          string
+		LFSURL        string
+		LFS           bool
+		Hosts         map[string]format.HostConfig
+		Requests      []string
+		Authorization string
+	}{
+		{"Disabled", "", false, nil, nil, ""},
+		{"Local", "", true, nil, nil, ""},
+		{"Server", server.URL + "/lfs", true, nil, serverRequests, ""},
+		{"ServerOnOtherHost", server.URL + "/lfs", true,
+			map[string]format.HostConfig{"": {Token: "repo-token"}},
+			serverRequests, ""},
+		{"ServerWithToken", server.URL + "/lfs", true,
+			map[string]format.HostConfig{serverURL.Hostname(): {Token: "lfs-token"}},
+			serverRequests, "Basic " + base64.StdEncoding.EncodeToString(
+				[]byte(tokenUsername+":lfs-token"))},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+			testDir, _ = filepath.Abs(testDir)
+			assert.Nil(os.Mkdir(testDir, 0700))
+			requests = nil
+			authorization = ""
+
+			upstream := makeLFSUpstream(t, filepath.Join(testDir, "upstream"), d.LFSURL)
+
+			var config format.ToolConfig
+			config.Getter.Options = map[string]interface{}{"lfs": d.LFS}
+			getter, err := NewGetter(
+				testStoic{root: testDir, cache: memoryCache{}, hosts: d.Hosts},
+				testTool{endpoint: upstream, config: config})
+			if err != nil {
+				t.Fatalf("unable to create getter: %v", err)
+			}
+
+			version, err := getter.FetchLatest()
+			assert.Nil(err)
+
+			checkoutDir := filepath.Join(testDir, "checkout")
+			assertCheckout(t, getter, version, checkoutDir, "v1.0.0")
+
+			content, err := ioutil.ReadFile(filepath.Join(checkoutDir, "asset"))
+			assert.Nil(err)
+			_, isPointer := parseLFSPointer(content)
+			assert.Equal(!d.LFS, isPointer)
+			if d.LFS {
+				assert.Equal(lfsContent, string(content))
+			}
+
+			assert.Equal(d.Authorization, authorization)
+			assert.Len(requests, len(d.Requests))
+			for i := range d.Requests {
+				if i < len(requests) {
+					assert.True(strings.HasPrefix(requests[i], d.Requests[i]), requests[i])
+				}
+			}
+
+			// Objects are cached
+			requests = nil
+			assertCheckout(t, getter, version, filepath.Join(testDir, "again"), "v1.0.0")
+			assert.Len(requests, 0)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package getter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"gopkg.in/src-d/go-git.v4"
+	gitplumbing "gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// isShallow is true if history is fetched partially, up to Depth commits
+// from the tips fetched.
+func (gg Getter) isShallow() bool {
+	return gg.Depth > 0
+}
+
+// nativeFetchArgs returns arguments for git fetch that limit what is fetched
+// according to the Depth and Filter options.
+func (gg Getter) nativeFetchArgs() []string {
+	var args []string
+	if gg.isShallow() {
+		args = append(args, "--depth="+strconv.Itoa(gg.Depth))
+	}
+	if gg.Filter != "" {
+		args = append(args, "--filter="+gg.Filter)
+	}
+	return args
+}
+
+// setupPromisorRemote configures the origin remote of the repository in
+// gitDir as the source of objects left out by Filter, which git then fetches
+// on demand.
+func (gg Getter) setupPromisorRemote(gitDir string) error {
+	url, _ := gg.URL.MarshalBinary()
+	for _, kv := range [][2]string{
+		{"core.repositoryformatversion", "1"},
+		{"extensions.partialclone", remoteName},
+		{"remote." + remoteName + ".url", string(url)},
+		{"remote." + remoteName + ".promisor", "true"},
+		{"remote." + remoteName + ".partialclonefilter", gg.Filter},
+	} {
+		err := runNativeGitIn(gitDir, "", "config", kv[0], kv[1])
+		if err != nil {
+			return errors.Wrapf(err, "unable to set %v in %v", kv[0], gitDir)
+		}
+	}
+	return nil
+}
+
+// reachableFrom is true if commit is reachable from tip in repo. The walk
+// stops at commits whose parents are missing, such as at the boundary of a
+// shallow history, in which case incomplete is true.
+func reachableFrom(repo *git.Repository, tip, commit gitplumbing.Hash) (reachable, incomplete bool, err error) {
+	seen := map[gitplumbing.Hash]bool{tip: true}
+	pending := []gitplumbing.Hash{tip}
+
+	for len(pending) != 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if hash == commit {
+			return true, false, nil
+		}
+
+		c, err := repo.CommitObject(hash)
+		if err == gitplumbing.ErrObjectNotFound {
+			incomplete = true
+			continue
+		}
+		if err != nil {
+			return false, false, err
+		}
+
+		for _, parent := range c.ParentHashes {
+			if !seen[parent] {
+				seen[parent] = true
+				pending = append(pending, parent)
+			}
+		}
+	}
+	return false, incomplete, nil
+}
+
+// fetchCommit fetches a single commit from upstream, by hash, up to Depth.
+// It is used to get pinned versions that are older than the shallow history
+// of the branch. Commits already fetched are not fetched again.
+//
+// Whether the commit is reachable from the branch can't be checked without
+// its full history. Servers are trusted to only serve commits that are
+// reachable from one of their references, which is what git servers allow by
+// default.
+func (gg Getter) fetchCommit(repo *git.Repository, commit gitplumbing.Hash) error {
+	if _, err := repo.CommitObject(commit); err == nil {
+		return nil
+	}
+
+	if !gg.usesNativeClient() {
+		return errors.Errorf(
+			"commit %.12v is not part of the shallow history fetched, and the "+
+				"builtin git client can't fetch commits by hash", commit.String())
+	}
+
+	jww.INFO.Printf("commit %.12v is not part of the shallow history fetched, "+
+		"fetching it from %v", commit.String(), redactURL(gg.URL))
+	return gg.fetchHash(commit)
+}
+
+// fetchHash fetches commit from upstream by hash, with the git command, and
+// keeps a reference to it.
+func (gg Getter) fetchHash(commit gitplumbing.Hash) error {
+	refspec := fmt.Sprintf("+%v:refs/pins/%v", commit, commit)
+	return gg.runNativeFetch(refspec)
+}
+
+// nativeCheckout updates the worktree at path to commit with the git command,
+// which fetches objects left out by Filter on demand.
+func (gg Getter) nativeCheckout(path string, commit gitplumbing.Hash) error {
+	dstGitDir := filepath.Join(path, ".git")
+	if err := gg.setupPromisorRemote(dstGitDir); err != nil {
+		return err
+	}
+
+	return runNativeGitIn(dstGitDir, path, "reset", "--quiet", "--hard", commit.String())
+}
@@ -0,0 +1,173 @@
+package getter
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	gitplumbing "gopkg.in/src-d/go-git.v4/plumbing"
+	gitfilemode "gopkg.in/src-d/go-git.v4/plumbing/filemode"
+)
+
+// scpLikeURLRegexp matches the scp-like syntax for SSH URLs (e.g.,
+// git@github.com:stoic-cli/stoic-cli-core.git).
+var scpLikeURLRegexp = regexp.MustCompile(`^(?:([^@/]+)@)?([^:/]+):(.*)$`)
+
+// checkoutSubmodules checks out the submodules of the worktree at path, at
+// the commits recorded in its index.
+func (gg Getter) checkoutSubmodules(path string) error {
+	content, err := ioutil.ReadFile(filepath.Join(path, ".gitmodules"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	modules := gitconfig.NewModules()
+	if err := modules.Unmarshal(content); err != nil {
+		return errors.Wrapf(err, "unable to parse .gitmodules in %v", path)
+	}
+
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return err
+	}
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	for _, module := range modules.Submodules {
+		entry, err := index.Entry(module.Path)
+		if err != nil || entry.Mode != gitfilemode.Submodule {
+			// Submodules that were removed may linger in .gitmodules
+			continue
+		}
+
+		sub, err := gg.submoduleGetter(module)
+		if err != nil {
+			return errors.Wrapf(err, "invalid submodule, '%v'", module.Name)
+		}
+
+		err = sub.fetchRecordedCommit(entry.Hash)
+		if err != nil {
+			return errors.Wrapf(err, "unable to fetch submodule '%v'", module.Name)
+		}
+
+		version := tool.Version(entry.Hash.String())
+		err = sub.CheckoutTo(version, filepath.Join(path, filepath.FromSlash(module.Path)))
+		if err != nil {
+			return errors.Wrapf(err, "unable to check out submodule '%v'", module.Name)
+		}
+	}
+	return nil
+}
+
+// submoduleGetter returns a getter for the repository of a submodule, which
+// shares the options of the getter of its superproject.
+func (gg Getter) submoduleGetter(module *gitconfig.Submodule) (*Getter, error) {
+	if err := module.Validate(); err != nil {
+		return nil, err
+	}
+
+	submoduleURL, err := resolveSubmoduleURL(gg.URL, module.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// As with git, "." tracks the branch of the superproject
+	branch := Branch(module.Branch)
+	if branch == "." {
+		branch = gg.Branch
+	}
+
+	return newGetter(gg.stoic, Options{
+		URL:        submoduleURL,
+		Branch:     branch,
+		Mode:       BranchMode,
+		Client:     gg.Client,
+		SSHKey:     gg.SSHKey,
+		Depth:      gg.Depth,
+		Filter:     gg.Filter,
+		Submodules: true,
+		LFS:        gg.LFS,
+	}, tool.NoConstraint)
+}
+
+// resolveSubmoduleURL returns the URL of a submodule, resolving URLs relative
+// to the superproject, which start with "./" or "../", as git does.
+func resolveSubmoduleURL(superproject *url.URL, submodule string) (*url.URL, error) {
+	if strings.HasPrefix(submodule, "./") || strings.HasPrefix(submodule, "../") {
+		resolved := *superproject
+		resolved.Path = path.Join(superproject.Path, submodule)
+		resolved.RawPath = ""
+		return &resolved, nil
+	}
+
+	if match := scpLikeURLRegexp.FindStringSubmatch(submodule); match != nil &&
+		!strings.Contains(submodule, "://") {
+		u := &url.URL{Scheme: "ssh", Host: match[2], Path: "/" + match[3]}
+		if match[1] != "" {
+			u.User = url.User(match[1])
+		}
+		return u, nil
+	}
+
+	u, err := url.Parse(submodule)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		if !filepath.IsAbs(submodule) {
+			return nil, errors.Errorf("unsupported submodule URL, '%v'", submodule)
+		}
+		u = &url.URL{Scheme: "file", Path: filepath.ToSlash(submodule)}
+	}
+	return u, nil
+}
+
+// fetchRecordedCommit makes commit available in the repository of the
+// submodule, fetching its branch if needed. Commits that are not part of the
+// branch are fetched by hash, which requires the git command.
+func (gg Getter) fetchRecordedCommit(commit gitplumbing.Hash) error {
+	gitDirLock, err := gg.lockGitDir()
+	if err != nil {
+		return err
+	}
+	defer gitDirLock.Unlock()
+
+	hasCommit := func() bool {
+		repo, err := git.PlainOpen(gg.gitDir)
+		if err != nil {
+			return false
+		}
+		_, err = repo.CommitObject(commit)
+		return err == nil
+	}
+
+	if hasCommit() {
+		return nil
+	}
+	if _, err := gg.fetch(); err != nil {
+		return err
+	}
+	if hasCommit() {
+		return nil
+	}
+
+	if !gg.usesNativeClient() {
+		return errors.Errorf("commit %.12v is not part of the %v branch, and the "+
+			"builtin git client can't fetch commits by hash",
+			commit.String(), gg.remoteReference())
+	}
+	return gg.fetchHash(commit)
+}
@@ -0,0 +1,118 @@
+package getter
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"gopkg.in/src-d/go-git.v4"
+	gitplumbing "gopkg.in/src-d/go-git.v4/plumbing"
+	gitstorer "gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+// tagsRefspec fetches all tags, replacing any that were moved upstream.
+const tagsRefspec = "+refs/tags/*:refs/tags/*"
+
+// listTags returns the names of all tags in repo.
+func listTags(repo *git.Repository) ([]tool.Version, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []tool.Version
+	err = refs.ForEach(func(ref *gitplumbing.Reference) error {
+		tags = append(tags, tool.Version(ref.Name().Short()))
+		return nil
+	})
+	return tags, err
+}
+
+// latestTag returns the name of the highest tag in repo, once tags are
+// filtered by the tag pattern and version constraint of the getter. Tags are
+// compared as versions, see tool.Version.Compare. Pre-release versions are
+// skipped unless the version constraint, which applies to tag names,
+// explicitly allows them.
+func (gg Getter) latestTag(repo *git.Repository) (tool.Version, error) {
+	tags, err := listTags(repo)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	latest, latestVersion := tool.NullVersion, tool.NullVersion
+	for _, tag := range tags {
+		version, ok := gg.tagVersion(tag)
+		if !ok {
+			continue
+		}
+
+		if gg.constraint.IsSet() {
+			if !gg.constraint.Allows(tag) {
+				continue
+			}
+		} else if sv, ok := version.Semver(); ok && sv.IsPrerelease() {
+			continue
+		}
+
+		if latest == tool.NullVersion || version.Compare(latestVersion) > 0 {
+			latest, latestVersion = tag, version
+		}
+	}
+
+	if latest == tool.NullVersion {
+		switch {
+		case gg.constraint.IsSet():
+			return tool.NullVersion, errors.Errorf(
+				"no tag satisfies version constraint '%v'", gg.constraint)
+		case gg.tagPattern != nil:
+			return tool.NullVersion, errors.Errorf(
+				"no tag matches pattern '%v'", gg.tagPattern)
+		}
+		return tool.NullVersion, errors.New("no tags found")
+	}
+	return latest, nil
+}
+
+// tagVersion returns the version that tag is compared by, and false if the
+// tag doesn't match the tag pattern.
+func (gg Getter) tagVersion(tag tool.Version) (tool.Version, bool) {
+	if gg.tagPattern == nil {
+		return tag, true
+	}
+
+	match := gg.tagPattern.FindStringSubmatch(string(tag))
+	switch {
+	case match == nil:
+		return tool.NullVersion, false
+	case len(match) > 1:
+		return tool.Version(match[1]), true
+	}
+	return tag, true
+}
+
+// resolveTag returns the hash of the commit tagged as name in repo, peeling
+// annotated tags.
+func resolveTag(repo *git.Repository, name tool.Version) (gitplumbing.Hash, error) {
+	refName := gitplumbing.ReferenceName("refs/tags/" + string(name))
+	ref, err := gitstorer.ResolveReference(repo.Storer, refName)
+	if err != nil {
+		return gitplumbing.ZeroHash, errors.Wrapf(err, "unable to resolve tag '%v'", name)
+	}
+
+	hash := ref.Hash()
+	for {
+		tag, err := repo.TagObject(hash)
+		if err == gitplumbing.ErrObjectNotFound {
+			// Not an annotated tag
+			break
+		}
+		if err != nil {
+			return gitplumbing.ZeroHash, err
+		}
+		hash = tag.Target
+	}
+
+	if _, err := repo.CommitObject(hash); err != nil {
+		return gitplumbing.ZeroHash, errors.Wrapf(err,
+			"tag '%v' doesn't point to a commit", name)
+	}
+	return hash, nil
+}
@@ -0,0 +1,121 @@
+package getter
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+// getToken returns a token for accessing the GitHub instance at host, or an
+// empty string if none is available. Tokens are looked up, in order, in the
+// environment, in the host configuration and from the configured credential
+// helper.
+//
+// GH_TOKEN and GITHUB_TOKEN only apply to github.com, as with the gh command.
+// GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN apply to other hosts.
+func getToken(s stoic.Stoic, host string) (string, error) {
+	envVars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "github.com" {
+		envVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, envVar := range envVars {
+		if token := os.Getenv(envVar); token != "" {
+			return token, nil
+		}
+	}
+
+	config := s.HostConfig(host)
+	if config.Token != "" {
+		return config.Token, nil
+	}
+	if config.CredentialHelper != "" {
+		return util.RunCredentialHelper(config.CredentialHelper, host)
+	}
+	return "", nil
+}
+
+// tokenTransport authenticates requests to the GitHub API host. Requests to
+// other hosts, such as those that asset downloads are redirected to, are left
+// untouched.
+type tokenTransport struct {
+	host  string
+	token string
+	base  http.RoundTripper
+}
+
+func (tt *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tt.token != "" && strings.EqualFold(req.URL.Host, tt.host) {
+		authReq := new(http.Request)
+		*authReq = *req
+		authReq.Header = make(http.Header, len(req.Header)+1)
+		for k, v := range req.Header {
+			authReq.Header[k] = v
+		}
+		authReq.Header.Set("Authorization", "token "+tt.token)
+		req = authReq
+	}
+	return tt.base.RoundTrip(req)
+}
+
+func rateLimitError(host string, reset time.Time, authenticated bool) error {
+	hint := ""
+	if !authenticated {
+		hint = "; set GITHUB_TOKEN, or configure a token for the host, " +
+			"to raise the limit"
+	}
+	if reset.IsZero() {
+		return errors.Errorf("GitHub API rate limit exceeded for %v%v", host, hint)
+	}
+	return errors.Errorf("GitHub API rate limit exceeded for %v, resets at %v%v",
+		host, reset.Local().Format(time.RFC3339), hint)
+}
+
+// checkRateLimit translates rate limit errors from the GitHub API into errors
+// that include the time at which the limit resets.
+func checkRateLimit(err error, host string, authenticated bool) error {
+	switch rle := err.(type) {
+	case *github.RateLimitError:
+		return rateLimitError(host, rle.Rate.Reset.Time, authenticated)
+
+	case *github.AbuseRateLimitError:
+		var reset time.Time
+		if rle.RetryAfter != nil {
+			reset = time.Now().Add(*rle.RetryAfter)
+		}
+		return rateLimitError(host, reset, authenticated)
+
+	case *github.ErrorResponse:
+		// Not all rate limit responses are recognized by go-github
+		if rle.Response != nil {
+			if rlErr := checkRateLimitResponse(rle.Response, host, authenticated); rlErr != nil {
+				return rlErr
+			}
+		}
+	}
+	return err
+}
+
+// checkRateLimitResponse returns an error if resp reports that the GitHub API
+// rate limit was exceeded.
+func checkRateLimitResponse(resp *http.Response, host string, authenticated bool) error {
+	if resp.StatusCode != http.StatusForbidden &&
+		resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+
+	var reset time.Time
+	if epoch, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(epoch, 0)
+	}
+	return rateLimitError(host, reset, authenticated)
+}
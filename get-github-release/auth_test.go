@@ -0,0 +1,175 @@
+package getter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func clearTokenEnv() func() {
+	var restore []func()
+	for _, envVar := range []string{
+		"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN",
+	} {
+		restore = append(restore, util.SetenvForTest(envVar, ""))
+	}
+
+	return func() {
+		for _, f := range restore {
+			f()
+		}
+	}
+}
+
+func TestGetToken(t *testing.T) {
+	defer clearTokenEnv()()
+
+	s := testStoic{hosts: map[string]format.HostConfig{
+		"github.com":         {Token: "config-token"},
+		"github.example.com": {CredentialHelper: `sh -c "echo helper-token-for-$0"`},
+		"broken.example.com": {CredentialHelper: "false"},
+	}}
+
+	t.Run("Config", func(t *testing.T) {
+		assert := assert.New(t)
+
+		token, err := getToken(s, "github.com")
+		assert.Nil(err)
+		assert.Equal("config-token", token)
+	})
+	t.Run("Environment", func(t *testing.T) {
+		assert := assert.New(t)
+		defer util.SetenvForTest("GITHUB_TOKEN", "env-token")()
+
+		token, err := getToken(s, "github.com")
+		assert.Nil(err)
+		assert.Equal("env-token", token)
+
+		defer util.SetenvForTest("GH_TOKEN", "gh-token")()
+
+		token, err = getToken(s, "github.com")
+		assert.Nil(err)
+		assert.Equal("gh-token", token)
+	})
+	t.Run("EnvironmentNotUsedForOtherHosts", func(t *testing.T) {
+		assert := assert.New(t)
+		defer util.SetenvForTest("GITHUB_TOKEN", "env-token")()
+
+		token, err := getToken(s, "other.example.com")
+		assert.Nil(err)
+		assert.Equal("", token)
+	})
+	t.Run("EnterpriseEnvironment", func(t *testing.T) {
+		assert := assert.New(t)
+		defer util.SetenvForTest("GH_ENTERPRISE_TOKEN", "enterprise-token")()
+
+		token, err := getToken(s, "github.example.com")
+		assert.Nil(err)
+		assert.Equal("enterprise-token", token)
+	})
+	t.Run("CredentialHelper", func(t *testing.T) {
+		assert := assert.New(t)
+
+		token, err := getToken(s, "github.example.com")
+		assert.Nil(err)
+		assert.Equal("helper-token-for-github.example.com", token)
+	})
+	t.Run("FailingCredentialHelper", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := getToken(s, "broken.example.com")
+		assert.NotNil(err)
+	})
+}
+
+func TestAuthenticatedRequests(t *testing.T) {
+	defer clearTokenEnv()()
+
+	var server *httptest.Server
+	var rateLimited bool
+
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	authorized := map[string]string{}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized[r.URL.Path] = r.Header.Get("Authorization")
+
+		if rateLimited {
+			w.Header().Set("X-RateLimit-Limit", "60")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v3/repos/stoic-cli/tool/releases/latest":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"tag_name": "v1.0.0",
+				"assets": []map[string]string{
+					{"name": "tool", "url": server.URL + "/assets/1"},
+				},
+			})
+		case "/assets/1":
+			w.Write([]byte("tool"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	endpoint, _ := url.Parse(server.URL + "/stoic-cli/tool")
+	config := format.ToolConfig{}
+	config.Getter.Options = map[string]interface{}{"asset": "tool"}
+
+	newGetter := func(token string) ghrGetter {
+		s := testStoic{
+			cache: memoryCache{},
+			hosts: map[string]format.HostConfig{
+				endpoint.Hostname(): {Token: token},
+			},
+		}
+		getter, err := NewGetter(s, testTool{endpoint: endpoint, config: config})
+		if err != nil {
+			t.Fatalf("unable to create getter: %v", err)
+		}
+		return *getter.(*ghrGetter)
+	}
+
+	t.Run("Token", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := newGetter("secret").FetchLatest()
+		assert.Nil(err)
+		assert.Equal("token secret", authorized["/api/v3/repos/stoic-cli/tool/releases/latest"])
+		assert.Equal("token secret", authorized["/assets/1"])
+	})
+	t.Run("Anonymous", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := newGetter("").FetchLatest()
+		assert.Nil(err)
+		assert.Equal("", authorized["/api/v3/repos/stoic-cli/tool/releases/latest"])
+		assert.Equal("", authorized["/assets/1"])
+	})
+	t.Run("RateLimited", func(t *testing.T) {
+		assert := assert.New(t)
+		rateLimited = true
+
+		_, err := newGetter("").FetchLatest()
+		if assert.NotNil(err) {
+			assert.Contains(err.Error(), "rate limit")
+			assert.Contains(err.Error(), resetAt.Local().Format(time.RFC3339))
+			assert.Contains(err.Error(), "GITHUB_TOKEN")
+		}
+	})
+}
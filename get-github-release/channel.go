@@ -0,0 +1,139 @@
+package getter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+const (
+	// StableChannel tracks the latest release that is not a pre-release, as
+	// does the default channel.
+	StableChannel = tool.Channel("stable")
+
+	// PrereleaseChannel tracks the newest release, including pre-releases.
+	PrereleaseChannel = tool.Channel("prerelease")
+
+	// prereleasePrefix marks channels with a tag filter that also consider
+	// pre-releases (e.g., prerelease:v2.).
+	prereleasePrefix = "prerelease:"
+)
+
+// releasesPerPage is the page size used when listing releases.
+const releasesPerPage = 100
+
+// releaseFilter selects the release to track for a channel.
+//
+// Channels other than the default, stable and prerelease ones are tag
+// filters: a regular expression (or, if invalid, a literal prefix) matched
+// against the start of release tags (e.g., v2.*). Tag filters only consider
+// releases that are not pre-releases, unless prefixed with "prerelease:".
+//
+// With a version constraint, the release with the highest tag satisfying the
+// constraint is selected, rather than the newest one.
+type releaseFilter struct {
+	prereleases bool
+	tagPattern  *regexp.Regexp
+	constraint  tool.VersionConstraint
+}
+
+func newReleaseFilter(tc tool.Channel, constraint tool.VersionConstraint) releaseFilter {
+	rf := newChannelFilter(tc)
+	rf.constraint = constraint
+	return rf
+}
+
+func newChannelFilter(tc tool.Channel) releaseFilter {
+	switch tc {
+	case tool.DefaultChannel, StableChannel:
+		return releaseFilter{}
+	case PrereleaseChannel:
+		return releaseFilter{prereleases: true}
+	}
+
+	filter := string(tc)
+	prereleases := strings.HasPrefix(filter, prereleasePrefix)
+	filter = strings.TrimPrefix(filter, prereleasePrefix)
+
+	pattern, err := regexp.Compile("^(?:" + filter + ")")
+	if err != nil {
+		pattern = regexp.MustCompile("^" + regexp.QuoteMeta(filter))
+	}
+
+	return releaseFilter{
+		prereleases: prereleases,
+		tagPattern:  pattern,
+	}
+}
+
+// usesLatestRelease is true if the filter selects the release reported as
+// latest by GitHub, which spares listing releases.
+func (rf releaseFilter) usesLatestRelease() bool {
+	return !rf.prereleases && rf.tagPattern == nil && !rf.constraint.IsSet()
+}
+
+func (rf releaseFilter) matches(release *github.RepositoryRelease) bool {
+	if release.GetDraft() {
+		return false
+	}
+	if release.GetPrerelease() && !rf.prereleases {
+		return false
+	}
+	if rf.tagPattern != nil && !rf.tagPattern.MatchString(release.GetTagName()) {
+		return false
+	}
+	return rf.constraint.Allows(tool.Version(release.GetTagName()))
+}
+
+// findLatestRelease returns the newest release matching the filter or, with
+// a version constraint, the matching release with the highest version.
+func (rf releaseFilter) findLatestRelease(repos *github.RepositoriesService, owner, repo string) (*github.RepositoryRelease, error) {
+	if rf.usesLatestRelease() {
+		release, _, err := repos.GetLatestRelease(context.Background(), owner, repo)
+		return release, err
+	}
+
+	matching := map[tool.Version]*github.RepositoryRelease{}
+	var versions []tool.Version
+
+	opts := &github.ListOptions{PerPage: releasesPerPage}
+	for {
+		// Releases are listed newest first
+		releases, resp, err := repos.ListReleases(context.Background(), owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range releases {
+			if !rf.matches(release) {
+				continue
+			}
+			if !rf.constraint.IsSet() {
+				return release, nil
+			}
+
+			version := tool.Version(release.GetTagName())
+			matching[version] = release
+			versions = append(versions, version)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if highest := rf.constraint.Highest(versions); highest != tool.NullVersion {
+		return matching[highest], nil
+	}
+	if rf.constraint.IsSet() {
+		return nil, errors.Errorf(
+			"no release of %v/%v matches the channel and version constraint '%v'",
+			owner, repo, rf.constraint)
+	}
+	return nil, errors.Errorf("no release of %v/%v matches the channel", owner, repo)
+}
@@ -0,0 +1,206 @@
+package getter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseFilter(t *testing.T) {
+	release := func(tag string, prerelease, draft bool) *github.RepositoryRelease {
+		return &github.RepositoryRelease{
+			TagName:    github.String(tag),
+			Prerelease: github.Bool(prerelease),
+			Draft:      github.Bool(draft),
+		}
+	}
+
+	data := []struct {
+		Name     string
+		Channel  tool.Channel
+		Latest   bool
+		Matching []*github.RepositoryRelease
+		Other    []*github.RepositoryRelease
+	}{
+		{
+			Name:    "Default",
+			Channel: tool.DefaultChannel,
+			Latest:  true,
+		},
+		{
+			Name:    "Stable",
+			Channel: StableChannel,
+			Latest:  true,
+		},
+		{
+			Name:    "Prerelease",
+			Channel: PrereleaseChannel,
+			Matching: []*github.RepositoryRelease{
+				release("v1.0.0", false, false),
+				release("v1.1.0-rc.1", true, false),
+			},
+			Other: []*github.RepositoryRelease{
+				release("v1.1.0", false, true),
+			},
+		},
+		{
+			Name:    "Pattern",
+			Channel: "v2.*",
+			Matching: []*github.RepositoryRelease{
+				release("v2.0.0", false, false),
+				release("v2.13.1", false, false),
+			},
+			Other: []*github.RepositoryRelease{
+				release("v1.2.0", false, false),
+				release("v2.1.0-rc.1", true, false),
+				release("release-v2.0.0", false, false),
+			},
+		},
+		{
+			Name:    "PrereleasePattern",
+			Channel: "prerelease:v2.",
+			Matching: []*github.RepositoryRelease{
+				release("v2.0.0", false, false),
+				release("v2.1.0-rc.1", true, false),
+			},
+			Other: []*github.RepositoryRelease{
+				release("v1.2.0-rc.1", true, false),
+			},
+		},
+		{
+			Name:    "InvalidPatternIsPrefix",
+			Channel: "v2.[",
+			Matching: []*github.RepositoryRelease{
+				release("v2.[0]", false, false),
+			},
+			Other: []*github.RepositoryRelease{
+				release("v2.0", false, false),
+			},
+		},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			filter := newReleaseFilter(test.Channel, tool.NoConstraint)
+			assert.Equal(test.Latest, filter.usesLatestRelease())
+			for _, r := range test.Matching {
+				assert.True(filter.matches(r), "release: %v", r.GetTagName())
+			}
+			for _, r := range test.Other {
+				assert.False(filter.matches(r), "release: %v", r.GetTagName())
+			}
+		})
+	}
+}
+
+func TestGetterChannels(t *testing.T) {
+	type asset struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	type release struct {
+		TagName    string  `json:"tag_name"`
+		Prerelease bool    `json:"prerelease"`
+		Draft      bool    `json:"draft"`
+		Assets     []asset `json:"assets"`
+	}
+
+	// Listed newest first, over pages of two releases
+	releases := []release{
+		{TagName: "v3.0.0", Draft: true},
+		{TagName: "v2.1.0-rc.1", Prerelease: true},
+		{TagName: "v2.0.1"},
+		{TagName: "v1.9.0"},
+		{TagName: "v2.0.0"},
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/assets/tool":
+			w.Write([]byte("tool"))
+
+		case "/api/v3/repos/stoic-cli/tool/releases/latest":
+			json.NewEncoder(w).Encode(releases[2])
+
+		case "/api/v3/repos/stoic-cli/tool/releases":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			start, end := 2*(page-1), 2*page
+			if end < len(releases) {
+				w.Header().Set("Link", "<"+server.URL+r.URL.Path+
+					"?page="+strconv.Itoa(page+1)+">; rel=\"next\"")
+			} else {
+				end = len(releases)
+			}
+			json.NewEncoder(w).Encode(releases[start:end])
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for i := range releases {
+		releases[i].Assets = []asset{{"tool", server.URL + "/assets/tool"}}
+	}
+
+	endpoint, _ := url.Parse(server.URL + "/stoic-cli/tool")
+	config := format.ToolConfig{}
+	config.Getter.Options = map[string]interface{}{"asset": "tool"}
+
+	data := []struct {
+		Name       string
+		Channel    tool.Channel
+		Constraint tool.VersionConstraint
+		Expected   tool.Version
+	}{
+		{"Default", tool.DefaultChannel, "", "v2.0.1"},
+		{"Stable", StableChannel, "", "v2.0.1"},
+		{"Prerelease", PrereleaseChannel, "", "v2.1.0-rc.1"},
+		{"Pattern", "v1.", "", "v1.9.0"},
+		{"PrereleasePattern", "prerelease:v2", "", "v2.1.0-rc.1"},
+		{"NoMatch", "v4.", "", tool.NullVersion},
+		{"Constraint", tool.DefaultChannel, "^1", "v1.9.0"},
+		{"ConstraintHighest", tool.DefaultChannel, ">=1.0, <3", "v2.0.1"},
+		{"ConstraintNotLatest", tool.DefaultChannel, "~2.0.0, !=2.0.1", "v2.0.0"},
+		{"PrereleaseConstraint", PrereleaseChannel, ">=2.1.0-rc.1", "v2.1.0-rc.1"},
+		{"StableConstraint", StableChannel, ">=2.1.0-rc.1", tool.NullVersion},
+		{"ConstraintNoMatch", tool.DefaultChannel, "^4", tool.NullVersion},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			config := config
+			config.Version = test.Constraint
+
+			getter, err := NewGetter(testStoic{cache: memoryCache{}},
+				testTool{endpoint: endpoint, config: config, channel: test.Channel})
+			if err != nil {
+				t.Fatalf("unable to create getter: %v", err)
+			}
+
+			version, err := getter.(*ghrGetter).FetchLatest()
+			if test.Expected == tool.NullVersion {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.Expected, version)
+		})
+	}
+}
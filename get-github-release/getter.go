@@ -2,11 +2,13 @@ package getter
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"text/template"
 
@@ -15,8 +17,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stoic-cli/stoic-cli-core"
 	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
 )
 
+// maxChecksumsSize limits the size of checksums assets.
+const maxChecksumsSize = 1024 * 1024
+
 func NewGetter(stoic stoic.Stoic, tool stoic.Tool) (tool.Getter, error) {
 	endpoint := tool.Endpoint()
 
@@ -31,39 +37,107 @@ func NewGetter(stoic stoic.Stoic, tool stoic.Tool) (tool.Getter, error) {
 		return nil, err
 	}
 
+	var checksumsTmpl *template.Template
+	if options.Checksums != "" {
+		checksumsTmpl, err = template.New("checksums").Parse(options.Checksums)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Assets were historically copied as is, which setup steps may rely on
+	extract := options.Extract
+	if extract == "" {
+		if options.StripComponents != 0 {
+			return nil, errors.New("strip-components requires extract to be set")
+		}
+		extract = util.ArchiveNone
+	}
+
 	return &ghrGetter{
-		Stoic:      stoic,
-		Endpoint:   endpoint,
-		AssetTempl: tmpl,
+		Stoic:          stoic,
+		Endpoint:       endpoint,
+		AssetTempl:     tmpl,
+		ChecksumsTempl: checksumsTmpl,
+		Releases:       newReleaseFilter(tool.Channel(), tool.Config().Version),
+		Extract: util.ExtractOptions{
+			Format:          extract,
+			StripComponents: options.StripComponents,
+		},
 	}, nil
 }
 
 type ghrGetterOptions struct {
 	Asset string
+
+	// Checksums is a template for the name of a release asset with the
+	// SHA-256 digest of the asset (e.g., checksums.txt or {{.Asset}}.sha256).
+	Checksums string
+
+	// Extract is the format of the asset, which is extracted into checkouts
+	// if set. With "auto", the format is inferred from the asset name. By
+	// default, the asset is copied as is.
+	Extract         string
+	StripComponents int `mapstructure:"strip-components"`
 }
 
 type ghrGetter struct {
-	Stoic      stoic.Stoic
-	Endpoint   *url.URL
-	AssetTempl *template.Template
+	Stoic          stoic.Stoic
+	Endpoint       *url.URL
+	AssetTempl     *template.Template
+	ChecksumsTempl *template.Template
+	Releases       releaseFilter
+	Extract        util.ExtractOptions
+}
+
+// ghrClient makes requests to the GitHub API, authenticated if a token is
+// available for the host.
+type ghrClient struct {
+	*http.Client
+	host          string
+	authenticated bool
 }
 
-func (gg ghrGetter) getRepositoriesServices() (*github.RepositoriesService, error) {
-	var client *github.Client
+func (gg ghrGetter) getClient() (ghrClient, error) {
+	token, err := getToken(gg.Stoic, gg.Endpoint.Hostname())
+	if err != nil {
+		return ghrClient{}, err
+	}
+
+	apiHost := gg.Endpoint.Host
+	if gg.Endpoint.Hostname() == "github.com" {
+		apiHost = "api.github.com"
+	}
+
+	return ghrClient{
+		Client: &http.Client{
+			Transport: &tokenTransport{
+				host:  apiHost,
+				token: token,
+				base:  http.DefaultTransport,
+			},
+		},
+		host:          gg.Endpoint.Hostname(),
+		authenticated: token != "",
+	}, nil
+}
+
+func (gg ghrGetter) getRepositoriesServices(client ghrClient) (*github.RepositoriesService, error) {
+	var ghClient *github.Client
 	var err error
 
 	if gg.Endpoint.Hostname() == "github.com" {
-		client = github.NewClient(nil)
+		ghClient = github.NewClient(client.Client)
 	} else {
 		apiBase, _ := url.Parse("/api/v3/")
 		baseURL := gg.Endpoint.ResolveReference(apiBase).String()
-		client, err = github.NewEnterpriseClient(baseURL, baseURL, nil)
+		ghClient, err = github.NewEnterpriseClient(baseURL, baseURL, client.Client)
 	}
 
 	if err != nil {
 		return nil, err
 	}
-	return client.Repositories, nil
+	return ghClient.Repositories, nil
 }
 
 func (gg ghrGetter) getOwnerRepo() (string, string) {
@@ -89,6 +163,20 @@ func (gg ghrGetter) getAssetName(version tool.Version) (string, error) {
 	return builder.String(), nil
 }
 
+func (gg ghrGetter) getChecksumsName(version tool.Version, assetName string) (string, error) {
+	var builder strings.Builder
+
+	parameters := gg.Stoic.Parameters()
+	parameters["Version"] = string(version)
+	parameters["Asset"] = assetName
+
+	err := gg.ChecksumsTempl.Execute(&builder, parameters)
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
 func (gg ghrGetter) getCacheKey(version tool.Version, assetName string) string {
 	host := gg.Endpoint.Hostname()
 	owner, repo := gg.getOwnerRepo()
@@ -96,8 +184,15 @@ func (gg ghrGetter) getCacheKey(version tool.Version, assetName string) string {
 		[]string{"ghr", host, owner, repo, string(version), assetName}, "/")
 }
 
-func (gg ghrGetter) getRelease(version tool.Version, wantLatest bool) (tool.Version, error) {
-	repos, err := gg.getRepositoriesServices()
+// getRelease looks up the release for version, or the latest one, and its
+// asset. The asset is downloaded to the cache if download is set.
+func (gg ghrGetter) getRelease(version tool.Version, wantLatest, download bool) (tool.Version, error) {
+	client, err := gg.getClient()
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	repos, err := gg.getRepositoriesServices(client)
 	if err != nil {
 		return tool.NullVersion, err
 	}
@@ -111,13 +206,13 @@ func (gg ghrGetter) getRelease(version tool.Version, wantLatest bool) (tool.Vers
 	var release *github.RepositoryRelease
 
 	if wantLatest {
-		release, _, err = repos.GetLatestRelease(context.Background(), owner, repo)
+		release, err = gg.Releases.findLatestRelease(repos, owner, repo)
 	} else {
 		release, _, err = repos.GetReleaseByTag(context.Background(), owner, repo, string(version))
 	}
 
 	if err != nil {
-		return tool.NullVersion, err
+		return tool.NullVersion, checkRateLimit(err, client.host, client.authenticated)
 	}
 
 	version = tool.Version(*release.TagName)
@@ -127,73 +222,182 @@ func (gg ghrGetter) getRelease(version tool.Version, wantLatest bool) (tool.Vers
 		return tool.NullVersion, err
 	}
 
-	for _, asset := range release.Assets {
-		if assetName == *asset.Name {
-			req, err := http.NewRequest("GET", *asset.URL, nil)
-			if err != nil {
-				return tool.NullVersion, err
-			}
-			req.Header.Add("Accept", "application/octet-stream")
+	asset := findAsset(release, assetName)
+	if asset == nil {
+		return tool.NullVersion, errors.Errorf(
+			"release '%v' has no asset matching '%v'", version, assetName)
+	}
+	if !download {
+		return version, nil
+	}
 
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				return tool.NullVersion, err
-			}
-			defer resp.Body.Close()
+	var expectedSha256 string
+	if gg.ChecksumsTempl != nil {
+		expectedSha256, err = gg.getAssetSha256(client, release, version, assetName)
+		if err != nil {
+			return tool.NullVersion, err
+		}
+	}
 
-			if resp.StatusCode != 200 {
-				return tool.NullVersion, errors.Errorf(
-					"unexpected HTTP status while fetching '%v' for version '%v' of '%v'",
-					assetName, version, gg.Endpoint)
-			}
+	body, err := gg.downloadAsset(client, asset, version)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	defer body.Close()
+
+	var reader io.Reader = body
+	if expectedSha256 != "" {
+		reader = util.NewSha256Reader(body, expectedSha256)
+	}
+
+	err = gg.Stoic.Cache().Put(gg.getCacheKey(version, assetName), reader)
+	if err != nil {
+		return tool.NullVersion, errors.Wrapf(err,
+			"unable to fetch '%v' for version '%v' of '%v'",
+			assetName, version, gg.Endpoint)
+	}
 
-			err = gg.Stoic.Cache().Put(gg.getCacheKey(version, assetName), resp.Body)
-			if err != nil {
-				return tool.NullVersion, err
+	return version, nil
+}
+
+func findAsset(release *github.RepositoryRelease, name string) *github.ReleaseAsset {
+	for i := range release.Assets {
+		if release.Assets[i].GetName() == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+func (gg ghrGetter) downloadAsset(client ghrClient, asset *github.ReleaseAsset, version tool.Version) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", asset.GetURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/octet-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRateLimitResponse(resp, client.host, client.authenticated); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, errors.Errorf(
+			"unexpected HTTP status while fetching '%v' for version '%v' of '%v'",
+			asset.GetName(), version, gg.Endpoint)
+	}
+	return resp.Body, nil
+}
+
+// getAssetSha256 returns the SHA-256 digest of an asset, as published in the
+// checksums asset of the release.
+func (gg ghrGetter) getAssetSha256(client ghrClient, release *github.RepositoryRelease, version tool.Version, assetName string) (string, error) {
+	checksumsName, err := gg.getChecksumsName(version, assetName)
+	if err != nil {
+		return "", err
+	}
+
+	asset := findAsset(release, checksumsName)
+	if asset == nil {
+		return "", errors.Errorf(
+			"release '%v' has no checksums asset matching '%v'", version, checksumsName)
+	}
+
+	body, err := gg.downloadAsset(client, asset, version)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(body, maxChecksumsSize))
+	if err != nil {
+		return "", err
+	}
+
+	digest, ok := parseChecksums(content, assetName)
+	if !ok {
+		return "", errors.Errorf(
+			"no sha256 checksum for '%v' found in '%v' for version '%v' of '%v'",
+			assetName, checksumsName, version, gg.Endpoint)
+	}
+	return digest, nil
+}
+
+// parseChecksums looks up the SHA-256 digest for assetName in the content of
+// a checksums file, in the format produced by sha256sum (both text and BSD
+// style). A file with a single digest and no file names, as commonly
+// published for individual assets, is also accepted.
+func parseChecksums(content []byte, assetName string) (string, bool) {
+	var digests []string
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		// BSD style, SHA256 (name) = digest
+		if strings.HasPrefix(line, "SHA256 (") {
+			pos := strings.LastIndex(line, ") = ")
+			if pos != -1 && line[len("SHA256 ("):pos] == assetName {
+				digest := line[pos+len(") = "):]
+				return digest, isSha256(digest)
 			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			digests = append(digests, fields[0])
 
-			return version, nil
+		case 2:
+			name := strings.TrimPrefix(fields[1], "*")
+			if name == assetName || path.Base(name) == assetName {
+				return fields[0], isSha256(fields[0])
+			}
 		}
 	}
 
-	return tool.NullVersion, errors.Errorf(
-		"release '%v' has no asset matching '%v'", version, assetName)
+	if len(digests) == 1 {
+		return digests[0], isSha256(digests[0])
+	}
+	return "", false
+}
+
+func isSha256(digest string) bool {
+	_, err := hex.DecodeString(digest)
+	return err == nil && len(digest) == 2*sha256.Size
 }
 
 func (gg ghrGetter) FetchLatest() (tool.Version, error) {
-	return gg.getRelease(tool.NullVersion, true)
+	return gg.getRelease(tool.NullVersion, true, true)
+}
+
+func (gg ghrGetter) LatestVersion() (tool.Version, error) {
+	return gg.getRelease(tool.NullVersion, true, false)
 }
 
 func (gg ghrGetter) FetchVersion(version tool.Version) error {
-	_, err := gg.getRelease(version, false)
+	_, err := gg.getRelease(version, false, true)
 	return err
 }
 
 func (gg ghrGetter) CheckoutTo(version tool.Version, path string) error {
 	assetName, err := gg.getAssetName(version)
-	if err != nil {
-		return nil
-	}
-
-	assetPath := filepath.Join(path, assetName)
-	asset, err := os.Create(assetPath)
 	if err != nil {
 		return err
 	}
-	defer asset.Close()
 
 	cacheReader := gg.Stoic.Cache().Get(gg.getCacheKey(version, assetName))
 	if cacheReader == nil {
-		return errors.Errorf(
+		return errors.Wrapf(tool.ErrVersionNotFetched,
 			"unable to retrieve '%v' for version '%v' of '%v' from cache",
 			assetName, version, gg.Endpoint)
 	}
 	defer cacheReader.Close()
 
-	_, err = io.Copy(asset, cacheReader)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return util.ExtractArchive(cacheReader, assetName, path, gg.Extract)
 }
@@ -0,0 +1,285 @@
+package getter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	toolSha256     = "7c9bbe5ec9b3fb774e8fa0f54247e93c34ddf8e5d16fe3073420de0ae81a262d"
+	tamperedSha256 = "d121be3103007b41edf96f8262925f8c7d61894afe9a041843b631f69445bc57"
+)
+
+type memoryCache map[string][]byte
+
+func (mc memoryCache) Get(key string) io.ReadCloser {
+	if data, ok := mc[key]; ok {
+		return ioutil.NopCloser(bytes.NewReader(data))
+	}
+	return nil
+}
+
+func (mc memoryCache) Put(key string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	mc[key] = data
+	return nil
+}
+
+type testStoic struct {
+	stoic.Stoic
+	cache memoryCache
+	hosts map[string]format.HostConfig
+}
+
+func (ts testStoic) Cache() stoic.Cache { return ts.cache }
+
+func (ts testStoic) HostConfig(host string) format.HostConfig {
+	return ts.hosts[host]
+}
+
+func (ts testStoic) Parameters() map[string]interface{} {
+	return map[string]interface{}{"OS": "plan9", "Arch": "mips"}
+}
+
+type testTool struct {
+	stoic.Tool
+	endpoint *url.URL
+	config   format.ToolConfig
+	channel  tool.Channel
+}
+
+func (tt testTool) Endpoint() *url.URL        { return tt.endpoint }
+func (tt testTool) Config() format.ToolConfig { return tt.config }
+func (tt testTool) Channel() tool.Channel     { return tt.channel }
+
+func TestParseChecksums(t *testing.T) {
+	data := []struct {
+		Name     string
+		Content  string
+		Expected string
+	}{
+		{
+			Name:     "Sha256sum",
+			Content:  tamperedSha256 + "  other.tar.gz\n" + toolSha256 + "  tool.tar.gz\n",
+			Expected: toolSha256,
+		},
+		{
+			Name:     "Sha256sumBinary",
+			Content:  toolSha256 + " *tool.tar.gz\n",
+			Expected: toolSha256,
+		},
+		{
+			Name:     "Sha256sumRelativePath",
+			Content:  toolSha256 + "  ./dist/tool.tar.gz\n",
+			Expected: toolSha256,
+		},
+		{
+			Name:     "BSD",
+			Content:  "SHA256 (tool.tar.gz) = " + toolSha256 + "\n",
+			Expected: toolSha256,
+		},
+		{
+			Name:     "DigestOnly",
+			Content:  toolSha256 + "\n",
+			Expected: toolSha256,
+		},
+		{
+			Name:    "NotListed",
+			Content: tamperedSha256 + "  other.tar.gz\n",
+		},
+		{
+			Name:    "NotSha256",
+			Content: "d41d8cd98f00b204e9800998ecf8427e  tool.tar.gz\n",
+		},
+		{
+			Name:    "Empty",
+			Content: "",
+		},
+	}
+
+	for _, test := range data {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			digest, ok := parseChecksums([]byte(test.Content), "tool.tar.gz")
+			assert.Equal(test.Expected != "", ok)
+			if ok {
+				assert.Equal(test.Expected, digest)
+			}
+		})
+	}
+}
+
+func TestGetterChecksums(t *testing.T) {
+	var server *httptest.Server
+
+	assets := map[string]string{
+		"tool-v1.0.0-plan9-mips":        "tool",
+		"tool-v1.0.0-plan9-mips.sha256": toolSha256 + "\n",
+		"checksums.txt":                 tamperedSha256 + "  tool-v1.0.0-plan9-mips\n",
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/repos/stoic-cli/tool/releases/tags/v1.0.0":
+			type asset struct {
+				Name string `json:"name"`
+				URL  string `json:"url"`
+			}
+			release := struct {
+				TagName string  `json:"tag_name"`
+				Assets  []asset `json:"assets"`
+			}{TagName: "v1.0.0"}
+
+			for name := range assets {
+				release.Assets = append(release.Assets,
+					asset{name, server.URL + "/assets/" + name})
+			}
+			json.NewEncoder(w).Encode(release)
+
+		case strings.HasPrefix(r.URL.Path, "/assets/"):
+			content, ok := assets[strings.TrimPrefix(r.URL.Path, "/assets/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, content)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	newGetter := func(checksums string) (tool.Getter, memoryCache) {
+		var config format.ToolConfig
+		err := yaml.Unmarshal([]byte(fmt.Sprintf(`
+getter:
+  type: github-release
+  asset: 'tool-{{.Version}}-{{.OS}}-{{.Arch}}'
+  checksums: '%v'`, checksums)), &config)
+		if err != nil {
+			t.Fatalf("unable to parse config: %v", err)
+		}
+
+		endpoint, _ := url.Parse(server.URL + "/stoic-cli/tool")
+		cache := memoryCache{}
+		getter, err := NewGetter(
+			testStoic{cache: cache}, testTool{endpoint: endpoint, config: config})
+		if err != nil {
+			t.Fatalf("unable to create getter: %v", err)
+		}
+		return getter, cache
+	}
+
+	t.Run("Match", func(t *testing.T) {
+		assert := assert.New(t)
+
+		getter, cache := newGetter("{{.Asset}}.sha256")
+		assert.Nil(getter.FetchVersion("v1.0.0"))
+		assert.Len(cache, 1)
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		assert := assert.New(t)
+
+		getter, cache := newGetter("checksums.txt")
+		err := getter.FetchVersion("v1.0.0")
+		if assert.NotNil(err) {
+			assert.Contains(err.Error(), tamperedSha256)
+			assert.Contains(err.Error(), toolSha256)
+		}
+		assert.Len(cache, 0)
+	})
+	t.Run("MissingChecksumsAsset", func(t *testing.T) {
+		assert := assert.New(t)
+
+		getter, cache := newGetter("SHA256SUMS")
+		assert.NotNil(getter.FetchVersion("v1.0.0"))
+		assert.Len(cache, 0)
+	})
+}
+
+func TestGetterExtract(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0755, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("tool"))
+	tw.Close()
+	gz.Close()
+
+	data := []struct {
+		Name    string
+		Options string
+		Files   []string
+		Error   bool
+	}{
+		{"Default", "", []string{"tool.tar.gz"}, false},
+		{"Auto", "extract: auto", []string{"tool"}, false},
+		{"Format", "extract: tar.gz", []string{"tool"}, false},
+		{"StripComponentsWithoutExtract", "strip-components: 1", nil, true},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+			assert.Nil(os.Mkdir(testDir, 0700))
+
+			var config format.ToolConfig
+			err := yaml.Unmarshal([]byte(fmt.Sprintf(`
+getter:
+  type: github-release
+  asset: tool.tar.gz
+  %v`, d.Options)), &config)
+			if err != nil {
+				t.Fatalf("unable to parse config: %v", err)
+			}
+
+			endpoint, _ := url.Parse("https://github.com/stoic-cli/tool")
+			cache := memoryCache{}
+			getter, err := NewGetter(
+				testStoic{cache: cache}, testTool{endpoint: endpoint, config: config})
+			if d.Error {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+
+			key := getter.(*ghrGetter).getCacheKey("v1.0.0", "tool.tar.gz")
+			cache[key] = archive.Bytes()
+			assert.Nil(getter.CheckoutTo("v1.0.0", testDir))
+
+			files, err := ioutil.ReadDir(testDir)
+			assert.Nil(err)
+			var names []string
+			for _, file := range files {
+				names = append(names, file.Name())
+			}
+			assert.Equal(d.Files, names)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package getter
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+// maxLatestVersionSize limits the size of responses from latest-url.
+const maxLatestVersionSize = 1024
+
+func NewGetter(stoic stoic.Stoic, tool stoic.Tool) (tool.Getter, error) {
+	var options httpGetterOptions
+	err := mapstructure.Decode(tool.Config().Getter.Options, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.URL == "" {
+		return nil, errors.Errorf("no url configured for '%v'", tool.Name())
+	}
+
+	urlTempl, err := template.New("url").Parse(options.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var latestURLTempl *template.Template
+	if options.LatestURL != "" {
+		latestURLTempl, err = template.New("latest-url").Parse(options.LatestURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &httpGetter{
+		Stoic:          stoic,
+		Endpoint:       tool.Endpoint(),
+		URLTempl:       urlTempl,
+		LatestURLTempl: latestURLTempl,
+		Sha256:         options.Sha256,
+		Extract: util.ExtractOptions{
+			Format:          options.Extract,
+			StripComponents: options.StripComponents,
+		},
+	}, nil
+}
+
+type httpGetterOptions struct {
+	// URL is a template for the URL of the artifact for a version, resolved
+	// relative to the tool endpoint.
+	URL string
+
+	// LatestURL is a template for a URL whose content is the latest version.
+	LatestURL string `mapstructure:"latest-url"`
+
+	// Sha256 maps versions to the expected SHA-256 digest of their artifacts.
+	// Platform specific digests are keyed by "<version>/<os>-<arch>".
+	Sha256 map[string]string
+
+	// Extract is the format of the artifact, which is extracted into
+	// checkouts. By default, it is inferred from the artifact name.
+	Extract         string
+	StripComponents int `mapstructure:"strip-components"`
+}
+
+type httpGetter struct {
+	Stoic          stoic.Stoic
+	Endpoint       *url.URL
+	URLTempl       *template.Template
+	LatestURLTempl *template.Template
+	Sha256         map[string]string
+	Extract        util.ExtractOptions
+}
+
+func (hg httpGetter) resolveURL(templ *template.Template, version tool.Version) (*url.URL, error) {
+	var builder strings.Builder
+
+	parameters := hg.Stoic.Parameters()
+	parameters["Version"] = string(version)
+
+	err := templ.Execute(&builder, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := url.Parse(builder.String())
+	if err != nil {
+		return nil, err
+	}
+	return hg.Endpoint.ResolveReference(ref), nil
+}
+
+func (hg httpGetter) getCacheKey(version tool.Version, artifactURL *url.URL) string {
+	return strings.Join([]string{
+		"http", artifactURL.Hostname(), string(version),
+		url.PathEscape(artifactURL.RequestURI())}, "/")
+}
+
+func (hg httpGetter) getSha256(version tool.Version) string {
+	parameters := hg.Stoic.Parameters()
+	platform := fmt.Sprintf("%v/%v-%v", version, parameters["OS"], parameters["Arch"])
+
+	if digest, ok := hg.Sha256[platform]; ok {
+		return digest
+	}
+	return hg.Sha256[string(version)]
+}
+
+func (hg httpGetter) download(version tool.Version) error {
+	artifactURL, err := hg.resolveURL(hg.URLTempl, version)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Get(artifactURL.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf(
+			"unexpected HTTP status while fetching version '%v' of '%v' from %v: %v",
+			version, hg.Endpoint, artifactURL, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if digest := hg.getSha256(version); digest != "" {
+		body = util.NewSha256Reader(body, digest)
+	} else if len(hg.Sha256) != 0 {
+		jww.WARN.Printf("no sha256 checksum configured for version '%v' of '%v', "+
+			"unable to verify %v", version, hg.Endpoint, artifactURL)
+	}
+
+	err = hg.Stoic.Cache().Put(hg.getCacheKey(version, artifactURL), body)
+	if err != nil {
+		return errors.Wrapf(err,
+			"unable to fetch version '%v' of '%v' from %v",
+			version, hg.Endpoint, artifactURL)
+	}
+	return nil
+}
+
+func (hg httpGetter) FetchLatest() (tool.Version, error) {
+	version, err := hg.LatestVersion()
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	err = hg.download(version)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	return version, nil
+}
+
+func (hg httpGetter) LatestVersion() (tool.Version, error) {
+	if hg.LatestURLTempl == nil {
+		return tool.NullVersion, errors.Errorf(
+			"no latest-url configured for '%v', a version must be pinned", hg.Endpoint)
+	}
+
+	latestURL, err := hg.resolveURL(hg.LatestURLTempl, tool.NullVersion)
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	resp, err := http.Get(latestURL.String())
+	if err != nil {
+		return tool.NullVersion, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return tool.NullVersion, errors.Errorf(
+			"unexpected HTTP status while fetching latest version of '%v' from %v: %v",
+			hg.Endpoint, latestURL, resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxLatestVersionSize))
+	if err != nil {
+		return tool.NullVersion, err
+	}
+
+	version := tool.Version(strings.TrimSpace(string(content)))
+	if version == tool.NullVersion {
+		return tool.NullVersion, errors.Errorf(
+			"no version found at %v for '%v'", latestURL, hg.Endpoint)
+	}
+	return version, nil
+}
+
+func (hg httpGetter) FetchVersion(version tool.Version) error {
+	return hg.download(version)
+}
+
+func (hg httpGetter) CheckoutTo(version tool.Version, checkoutPath string) error {
+	artifactURL, err := hg.resolveURL(hg.URLTempl, version)
+	if err != nil {
+		return err
+	}
+
+	cacheReader := hg.Stoic.Cache().Get(hg.getCacheKey(version, artifactURL))
+	if cacheReader == nil {
+		return errors.Wrapf(tool.ErrVersionNotFetched,
+			"unable to retrieve %v for version '%v' of '%v' from cache",
+			artifactURL, version, hg.Endpoint)
+	}
+	defer cacheReader.Close()
+
+	artifactName := path.Base(artifactURL.Path)
+	if artifactName == "/" || artifactName == "." {
+		return errors.Errorf("unable to determine file name from %v", artifactURL)
+	}
+
+	return util.ExtractArchive(cacheReader, artifactName, checkoutPath, hg.Extract)
+}
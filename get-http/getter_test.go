@@ -0,0 +1,203 @@
+package getter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/format"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"gopkg.in/yaml.v2"
+)
+
+type memoryCache map[string][]byte
+
+func (mc memoryCache) Get(key string) io.ReadCloser {
+	if data, ok := mc[key]; ok {
+		return ioutil.NopCloser(bytes.NewReader(data))
+	}
+	return nil
+}
+
+func (mc memoryCache) Put(key string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	mc[key] = data
+	return nil
+}
+
+type testStoic struct {
+	stoic.Stoic
+	cache memoryCache
+}
+
+func (ts testStoic) Cache() stoic.Cache { return ts.cache }
+
+func (ts testStoic) Parameters() map[string]interface{} {
+	return map[string]interface{}{"OS": "plan9", "Arch": "mips"}
+}
+
+type testTool struct {
+	stoic.Tool
+	endpoint *url.URL
+	config   format.ToolConfig
+}
+
+func (tt testTool) Name() string              { return "test" }
+func (tt testTool) Endpoint() *url.URL        { return tt.endpoint }
+func (tt testTool) Config() format.ToolConfig { return tt.config }
+
+func TestHTTPGetter(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	artifacts := map[string]string{
+		"/releases/tool-v1.0.0-plan9-mips": "v1.0.0",
+		"/releases/tool-v2.0.0-plan9-mips": "v2.0.0",
+		"/releases/tool-v3.0.0-plan9-mips": "tampered",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/releases/latest.txt" {
+			fmt.Fprintln(w, "v2.0.0")
+			return
+		}
+		if content, ok := artifacts[r.URL.Path]; ok {
+			fmt.Fprint(w, content)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	newGetter := func(config string) (tool.Getter, memoryCache, error) {
+		var toolConfig format.ToolConfig
+		err := yaml.Unmarshal([]byte(config), &toolConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		endpoint, _ := url.Parse(server.URL + "/releases/")
+		cache := memoryCache{}
+		getter, err := NewGetter(
+			testStoic{cache: cache}, testTool{endpoint: endpoint, config: toolConfig})
+		return getter, cache, err
+	}
+
+	const (
+		v1Digest = "2485f4d55aae6c5b073114bc4c4b1907c0abae14166281beee7d93f76ebf41fc"
+		v2Digest = "1cf887de9e8df1530de6a3a80e5fb8cef3c26c5248611921bd860c85313a688f"
+		v3Digest = "7b869d3b91a5a44252a7ad88e94dc424bf208a19f711cc73676ae04ca1494233"
+	)
+
+	config := fmt.Sprintf(`
+getter:
+  type: http
+  url: 'tool-{{.Version}}-{{.OS}}-{{.Arch}}'
+  latest-url: latest.txt
+  sha256:
+    v2.0.0: %v
+    v3.0.0: %v`, v2Digest, v3Digest)
+
+	t.Run("MissingURL", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		_, _, err := newGetter(`getter: http`)
+		assert.NotNil(err)
+	})
+	t.Run("FetchLatest", func(t *testing.T) {
+		assert, testName := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testName, 0700))
+
+		getter, cache, err := newGetter(config)
+		assert.Nil(err)
+
+		version, err := getter.FetchLatest()
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), version)
+		assert.Len(cache, 1)
+
+		err = getter.CheckoutTo(version, testName)
+		assert.Nil(err)
+
+		content, err := ioutil.ReadFile(
+			filepath.Join(testName, "tool-v2.0.0-plan9-mips"))
+		assert.Nil(err)
+		assert.Equal("v2.0.0", string(content))
+	})
+	t.Run("LatestVersion", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		getter, cache, err := newGetter(config)
+		assert.Nil(err)
+
+		version, err := getter.(tool.LatestVersionGetter).LatestVersion()
+		assert.Nil(err)
+		assert.Equal(tool.Version("v2.0.0"), version)
+		assert.Len(cache, 0)
+	})
+	t.Run("NoLatestURL", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		getter, _, err := newGetter(`
+getter:
+  type: http
+  url: 'tool-{{.Version}}-{{.OS}}-{{.Arch}}'`)
+		assert.Nil(err)
+
+		_, err = getter.FetchLatest()
+		assert.NotNil(err)
+	})
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		assert, testName := tid.SetupTest(t)
+
+		getter, cache, err := newGetter(config)
+		assert.Nil(err)
+
+		err = getter.FetchVersion("v3.0.0")
+		assert.IsType(&util.ChecksumMismatchError{}, errors.Cause(err))
+		assert.Len(cache, 0)
+
+		err = getter.CheckoutTo("v3.0.0", testName)
+		assert.Equal(tool.ErrVersionNotFetched, errors.Cause(err))
+	})
+	t.Run("PlatformChecksum", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		// The platform specific checksum takes precedence over the one for
+		// the version, and matches.
+		getter, cache, err := newGetter(fmt.Sprintf(`
+getter:
+  type: http
+  url: 'tool-{{.Version}}-{{.OS}}-{{.Arch}}'
+  sha256:
+    v1.0.0: %v
+    v1.0.0/plan9-mips: %v`, v2Digest, v1Digest))
+		assert.Nil(err)
+
+		err = getter.FetchVersion("v1.0.0")
+		assert.Nil(err)
+		assert.Len(cache, 1)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		getter, cache, err := newGetter(config)
+		assert.Nil(err)
+
+		err = getter.FetchVersion("v4.0.0")
+		assert.NotNil(err)
+		assert.Len(cache, 0)
+	})
+}
@@ -0,0 +1,284 @@
+package runner
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// platform is the OS and architecture an executable is built for. The OS is
+// left empty for ELF executables, which don't reliably record it.
+type platform struct {
+	OS, Arch string
+}
+
+func (p platform) String() string {
+	if p.OS == "" {
+		return "ELF/" + p.Arch
+	}
+	return p.OS + "/" + p.Arch
+}
+
+// compatibleArchs lists architectures, other than its own, that executables
+// can be built for to run on a host.
+var compatibleArchs = map[string][]string{
+	"linux/amd64":   {"386"},
+	"windows/amd64": {"386"},
+	"darwin/arm64":  {"amd64"},
+	"windows/arm64": {"amd64", "386"},
+}
+
+var elfMachines = map[elf.Machine]string{
+	elf.EM_386:     "386",
+	elf.EM_X86_64:  "amd64",
+	elf.EM_ARM:     "arm",
+	elf.EM_AARCH64: "arm64",
+	elf.EM_PPC64:   "ppc64",
+	elf.EM_S390:    "s390x",
+	elf.EM_MIPS:    "mips",
+	elf.EM_RISCV:   "riscv64",
+}
+
+var machoCpus = map[macho.Cpu]string{
+	macho.Cpu386:   "386",
+	macho.CpuAmd64: "amd64",
+	macho.CpuArm:   "arm",
+	macho.CpuArm64: "arm64",
+	macho.CpuPpc64: "ppc64",
+}
+
+var peMachines = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+}
+
+// binaryPlatforms reads the header of file to return the platforms it is built
+// for, more than one for universal Mach-O binaries, and whether it is an
+// executable, rather than a shared library, an object or a core file. It
+// returns false if file is not an ELF, Mach-O or PE binary, such as a script.
+func binaryPlatforms(file string) ([]platform, bool, bool) {
+	if f, err := elf.Open(file); err == nil {
+		defer f.Close()
+		arch := elfMachines[f.Machine]
+		switch arch {
+		case "":
+			arch = strings.ToLower(strings.TrimPrefix(f.Machine.String(), "EM_"))
+		case "ppc64":
+			if f.Data == elf.ELFDATA2LSB {
+				arch = "ppc64le"
+			}
+		case "mips":
+			if f.Class == elf.ELFCLASS64 {
+				arch = "mips64"
+			}
+			if f.Data == elf.ELFDATA2LSB {
+				arch += "le"
+			}
+		}
+		return []platform{{Arch: arch}}, isELFExecutable(f), true
+	}
+
+	if f, err := macho.Open(file); err == nil {
+		defer f.Close()
+		platforms := []platform{{OS: "darwin", Arch: machoArch(f.Cpu)}}
+		return platforms, f.Type == macho.TypeExec, true
+	}
+	if f, err := macho.OpenFat(file); err == nil {
+		defer f.Close()
+		var platforms []platform
+		executable := false
+		for _, arch := range f.Arches {
+			platforms = append(platforms, platform{OS: "darwin", Arch: machoArch(arch.Cpu)})
+			executable = executable || arch.Type == macho.TypeExec
+		}
+		return platforms, executable, true
+	}
+
+	if f, err := pe.Open(file); err == nil {
+		defer f.Close()
+		arch, ok := peMachines[f.Machine]
+		if !ok {
+			arch = fmt.Sprintf("0x%x", f.Machine)
+		}
+		executable := f.Characteristics&pe.IMAGE_FILE_EXECUTABLE_IMAGE != 0 &&
+			f.Characteristics&pe.IMAGE_FILE_DLL == 0
+		return []platform{{OS: "windows", Arch: arch}}, executable, true
+	}
+
+	return nil, false, false
+}
+
+// isELFExecutable is true for ELF executables, including position-independent
+// ones, which have the same type as shared libraries but, unlike them, an
+// interpreter or an entry point.
+func isELFExecutable(f *elf.File) bool {
+	switch f.Type {
+	case elf.ET_EXEC:
+		return true
+	case elf.ET_DYN:
+		if f.Entry != 0 {
+			return true
+		}
+		for _, prog := range f.Progs {
+			if prog.Type == elf.PT_INTERP {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func machoArch(cpu macho.Cpu) string {
+	if arch, ok := machoCpus[cpu]; ok {
+		return arch
+	}
+	return strings.ToLower(strings.TrimPrefix(cpu.String(), "Cpu"))
+}
+
+// runsOn is true if an executable built for p runs on goos/goarch.
+func (p platform) runsOn(goos, goarch string) bool {
+	switch p.OS {
+	case "":
+		if goos == "darwin" || goos == "windows" {
+			return false
+		}
+	case "darwin":
+		if goos != "darwin" && goos != "ios" {
+			return false
+		}
+	default:
+		if p.OS != goos {
+			return false
+		}
+	}
+
+	if p.Arch == goarch {
+		return true
+	}
+	for _, arch := range compatibleArchs[goos+"/"+goarch] {
+		if p.Arch == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlatform returns an error if file is an executable built for another
+// platform than the host. Files that are not ELF, Mach-O or PE executables,
+// such as scripts, are not checked.
+func checkPlatform(file string) error {
+	platforms, _, ok := binaryPlatforms(file)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, p := range platforms {
+		if p.runsOn(runtime.GOOS, runtime.GOARCH) {
+			return nil
+		}
+		names = append(names, p.String())
+	}
+	return errors.Errorf("%v is built for %v, which can't run on %v/%v",
+		file, strings.Join(names, ", "), runtime.GOOS, runtime.GOARCH)
+}
+
+// makeExecutable adds execute permissions to file, for those who can read it.
+func makeExecutable(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	mode := info.Mode().Perm()
+	executable := mode | (mode&0444)>>2
+	if executable == mode {
+		return nil
+	}
+	return os.Chmod(file, executable)
+}
+
+// findExecutable returns the executable in the checkout at path. Executables
+// are native executable binaries, or files with execute permissions if there
+// are none.
+// If there is more than one, the only one that runs on the host or the one
+// named after the tool is picked.
+func findExecutable(path, toolName string) (string, error) {
+	var binaries, executables []string
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if _, executable, ok := binaryPlatforms(file); ok {
+			// Shared libraries, and such, shipped along with the executable
+			if executable {
+				binaries = append(binaries, file)
+			}
+		} else if info.Mode().Perm()&0111 != 0 {
+			executables = append(executables, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	candidates := binaries
+	if len(candidates) == 0 {
+		candidates = executables
+	}
+
+	if len(candidates) > 1 {
+		var runnable []string
+		for _, file := range candidates {
+			if checkPlatform(file) == nil {
+				runnable = append(runnable, file)
+			}
+		}
+		if len(runnable) != 0 {
+			candidates = runnable
+		}
+	}
+
+	if len(candidates) > 1 {
+		for _, file := range candidates {
+			name := strings.TrimSuffix(filepath.Base(file), ".exe")
+			if name == toolName {
+				return file, nil
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", errors.Errorf("no executable found in %v", path)
+	case 1:
+		return candidates[0], nil
+	}
+
+	for i := range candidates {
+		candidates[i], _ = filepath.Rel(path, candidates[i])
+	}
+	return "", errors.Errorf("more than one executable found in %v, "+
+		"set path to select one: %v", path, strings.Join(candidates, ", "))
+}
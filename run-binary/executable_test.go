@@ -0,0 +1,198 @@
+package runner
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeELF writes the header of an ELF file of type typ for machine, with the
+// given entry point.
+func writeELF(t *testing.T, file string, typ elf.Type, machine elf.Machine, entry uint64) {
+	header := elf.Header64{
+		Type:    uint16(typ),
+		Machine: uint16(machine),
+		Version: uint32(elf.EV_CURRENT),
+		Entry:   entry,
+		Ehsize:  64,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, header)
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// copyTestBinary copies the test binary, which runs on the host, to file.
+func copyTestBinary(t *testing.T, file string) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckPlatform(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	copyTestBinary(t, "host")
+	writeELF(t, "s390x", elf.ET_EXEC, elf.EM_S390, 0)
+	writeELF(t, "pie", elf.ET_DYN, elf.EM_S390, 0x1000)
+	writeELF(t, "lib.so", elf.ET_DYN, elf.EM_S390, 0)
+	writeELF(t, "main.o", elf.ET_REL, elf.EM_S390, 0)
+	assert.Nil(t, ioutil.WriteFile("script", []byte("#!/bin/sh\n"), 0755))
+
+	platforms, executable, ok := binaryPlatforms("s390x")
+	assert.True(t, ok)
+	assert.True(t, executable)
+	assert.Equal(t, []platform{{Arch: "s390x"}}, platforms)
+
+	_, executable, ok = binaryPlatforms("pie")
+	assert.True(t, ok)
+	assert.True(t, executable)
+
+	for _, file := range []string{"lib.so", "main.o"} {
+		_, executable, ok = binaryPlatforms(file)
+		assert.True(t, ok, file)
+		assert.False(t, executable, file)
+	}
+
+	_, _, ok = binaryPlatforms("script")
+	assert.False(t, ok)
+
+	assert.Nil(t, checkPlatform("host"))
+	assert.Nil(t, checkPlatform("script"))
+	if runtime.GOARCH != "s390x" {
+		assert.NotNil(t, checkPlatform("s390x"))
+	}
+}
+
+func TestPlatformRunsOn(t *testing.T) {
+	data := []struct {
+		Name     string
+		Platform platform
+		OS, Arch string
+		Expected bool
+	}{
+		{"ELF", platform{Arch: "amd64"}, "linux", "amd64", true},
+		{"ELFOnDarwin", platform{Arch: "arm64"}, "darwin", "arm64", false},
+		{"OtherArch", platform{Arch: "arm64"}, "linux", "amd64", false},
+		{"Compatible", platform{Arch: "386"}, "linux", "amd64", true},
+		{"Rosetta", platform{OS: "darwin", Arch: "amd64"}, "darwin", "arm64", true},
+		{"MachOOnLinux", platform{OS: "darwin", Arch: "amd64"}, "linux", "amd64", false},
+		{"PE", platform{OS: "windows", Arch: "amd64"}, "windows", "amd64", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert.Equal(t, d.Expected, d.Platform.runsOn(d.OS, d.Arch))
+		})
+	}
+}
+
+func TestFindExecutable(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	otherELF := elf.EM_S390
+	if runtime.GOARCH == "s390x" {
+		otherELF = elf.EM_X86_64
+	}
+
+	// Shared libraries built for the host are not executables either
+	hostELF := otherELF
+	if self, err := elf.Open(os.Args[0]); err == nil {
+		hostELF = self.Machine
+		self.Close()
+	}
+
+	data := []struct {
+		Name     string
+		Setup    func(dir string)
+		Expected string
+	}{
+		{"Single", func(dir string) {
+			copyTestBinary(t, filepath.Join(dir, "tool"))
+			ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644)
+			ioutil.WriteFile(filepath.Join(dir, "install.sh"), nil, 0755)
+		}, "tool"},
+		{"Nested", func(dir string) {
+			os.MkdirAll(filepath.Join(dir, "tool-1.0", "bin"), 0755)
+			copyTestBinary(t, filepath.Join(dir, "tool-1.0", "bin", "tool"))
+		}, filepath.Join("tool-1.0", "bin", "tool")},
+		{"Script", func(dir string) {
+			ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644)
+			ioutil.WriteFile(filepath.Join(dir, "tool.sh"), []byte("#!/bin/sh\n"), 0755)
+		}, "tool.sh"},
+		{"Runnable", func(dir string) {
+			copyTestBinary(t, filepath.Join(dir, "tool-host"))
+			writeELF(t, filepath.Join(dir, "tool-other"), elf.ET_EXEC, otherELF, 0)
+		}, "tool-host"},
+		{"SharedLibrary", func(dir string) {
+			os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+			copyTestBinary(t, filepath.Join(dir, "app"))
+			writeELF(t, filepath.Join(dir, "lib", "libapp.so.1"), elf.ET_DYN, hostELF, 0)
+			os.Chmod(filepath.Join(dir, "lib", "libapp.so.1"), 0755)
+		}, "app"},
+		{"ToolName", func(dir string) {
+			copyTestBinary(t, filepath.Join(dir, "tool"))
+			copyTestBinary(t, filepath.Join(dir, "helper"))
+		}, "tool"},
+		{"Ambiguous", func(dir string) {
+			copyTestBinary(t, filepath.Join(dir, "one"))
+			copyTestBinary(t, filepath.Join(dir, "two"))
+		}, ""},
+		{"None", func(dir string) {
+			ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644)
+		}, ""},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			assert, testDir := tid.SetupTest(t)
+			testDir = filepath.Join(tid.TestDir(), testDir)
+			assert.Nil(os.Mkdir(testDir, 0755))
+			d.Setup(testDir)
+
+			file, err := findExecutable(testDir, "tool")
+			if d.Expected == "" {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(filepath.Join(testDir, d.Expected), file)
+		})
+	}
+}
+
+func TestMakeExecutable(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	assert.Nil(t, ioutil.WriteFile("tool", nil, 0640))
+	assert.Nil(t, makeExecutable("tool"))
+
+	info, err := os.Stat("tool")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+}
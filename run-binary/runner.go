@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core"
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+)
+
+func NewRunner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+	var options Options
+	err := mapstructure.Decode(t.Config().Runner.Options, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathTempl *template.Template
+	if options.Path != "" {
+		pathTempl, err = template.New("path").Parse(options.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid executable path, '%v'", options.Path)
+		}
+	}
+
+	if _, ok := t.Config().Runner.Options["command"]; ok {
+		return nil, errors.New("the binary runner runs the executable in the " +
+			"checkout, use path to select it instead of command")
+	}
+
+	shellRunner, err := shell.NewRunner(s, t)
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := shellRunner.(shell.Runner)
+	if !ok {
+		return nil, fmt.Errorf(
+			"unable to cast shell runner of type %T to shell.Runner",
+			shellRunner)
+	}
+
+	sr.Options.Command = "{{.Binary}}"
+	return runner{sr, t.Name(), pathTempl}, nil
+}
+
+// executableBase names the file in checkouts that records the path of the
+// executable found by Setup, relative to the checkout.
+const executableBase = ".executable"
+
+type Options struct {
+	// Path is a template for the path of the executable in the checkout
+	// (e.g., bin/tool{{.WindowsExe}}). By default, the checkout must hold a
+	// single executable, which is run.
+	Path string
+}
+
+type runner struct {
+	ShellRunner shell.Runner
+	ToolName    string
+	PathTempl   *template.Template
+}
+
+// executable returns the absolute path of the executable in checkout. Unless
+// path is set, the executable found by Setup is used, if recorded.
+func (r runner) executable(checkout tool.Checkout) (string, error) {
+	if r.PathTempl == nil {
+		content, err := ioutil.ReadFile(filepath.Join(checkout.Path(), executableBase))
+		if err == nil {
+			return filepath.Join(checkout.Path(), filepath.FromSlash(string(content))), nil
+		}
+		return findExecutable(checkout.Path(), r.ToolName)
+	}
+
+	parameters := r.ShellRunner.Stoic.Parameters()
+	parameters["Checkout"] = checkout.Path()
+	parameters["Version"] = string(checkout.Version())
+
+	var builder strings.Builder
+	if err := r.PathTempl.Execute(&builder, parameters); err != nil {
+		return "", err
+	}
+
+	path := filepath.FromSlash(builder.String())
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(checkout.Path(), path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrap(err, "executable not found in checkout")
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.Errorf("%v is not an executable file", path)
+	}
+	return path, nil
+}
+
+func (r runner) Setup(checkout tool.Checkout) error {
+	path, err := r.executable(checkout)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPlatform(path); err != nil {
+		return err
+	}
+	if err := makeExecutable(path); err != nil {
+		return err
+	}
+
+	// Spare runs from looking for the executable again
+	if r.PathTempl == nil {
+		rel, err := filepath.Rel(checkout.Path(), path)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(checkout.Path(), executableBase),
+				[]byte(filepath.ToSlash(rel)), 0644)
+		}
+		if err != nil {
+			jww.DEBUG.Printf("failed to record executable in %v: %v", checkout.Path(), err)
+		}
+	}
+
+	r.ShellRunner.Options.SetupParameters["Binary"] = path
+	return r.ShellRunner.Setup(checkout)
+}
+
+func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
+	path, err := r.executable(checkout)
+	if err != nil {
+		return err
+	}
+
+	r.ShellRunner.Options.Parameters["Binary"] = path
+	return r.ShellRunner.Run(checkout, name, args)
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCheckout string
+
+func (tc testCheckout) Path() string          { return string(tc) }
+func (tc testCheckout) Version() tool.Version { return "v1.0.0" }
+
+func TestRunnerRecordsExecutable(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	checkout := filepath.Join(tid.TestDir(), "checkout")
+	assert.Nil(t, os.MkdirAll(filepath.Join(checkout, "bin"), 0755))
+	copyTestBinary(t, filepath.Join(checkout, "bin", "tool"))
+	ioutil.WriteFile(filepath.Join(checkout, "README"), nil, 0644)
+
+	r := runner{
+		ShellRunner: shell.Runner{
+			Options: shell.Options{
+				SetupParameters: map[string]interface{}{},
+			},
+		},
+		ToolName: "unrelated",
+	}
+	assert.Nil(t, r.Setup(testCheckout(checkout)))
+
+	content, err := ioutil.ReadFile(filepath.Join(checkout, executableBase))
+	assert.Nil(t, err)
+	assert.Equal(t, "bin/tool", string(content))
+
+	// Runs don't look for the executable again, which is now ambiguous
+	copyTestBinary(t, filepath.Join(checkout, "bin", "other"))
+
+	path, err := r.executable(testCheckout(checkout))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(checkout, "bin", "tool"), path)
+}
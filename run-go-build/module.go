@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var majorVersionRegexp = regexp.MustCompile(`^v[0-9]+$`)
+
+// binaryName returns the name go build gives the binary for the package at
+// importPath, skipping any major version suffix (e.g., foo for
+// example.com/foo/v2).
+func binaryName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersionRegexp.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	return name
+}
+
+// module is a Go module in a checkout.
+type module struct {
+	// Dir is the directory holding go.mod.
+	Dir string
+
+	// Path is the module path declared in go.mod.
+	Path string
+}
+
+// findModule looks for the Go module that holds the package at importPath in
+// a checkout. Checkouts are laid out as a GOPATH, with the repository under
+// src, or hold the repository at their root. It returns false if the package
+// is not part of a module, and should be built in GOPATH mode.
+func findModule(checkoutPath, importPath string) (module, bool) {
+	checkoutPath = filepath.Clean(checkoutPath)
+
+	// Packages in modules with a major version suffix are not in a
+	// directory of that name, start from the deepest directory that exists
+	dir := filepath.Join(checkoutPath, "src", filepath.FromSlash(importPath))
+	for dir != checkoutPath {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		if modulePath, ok := readModulePath(filepath.Join(dir, "go.mod")); ok {
+			return module{Dir: dir, Path: modulePath}, true
+		}
+		if dir == checkoutPath || dir == filepath.Dir(dir) {
+			return module{}, false
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readModulePath returns the module path declared in a go.mod file.
+func readModulePath(goMod string) (string, bool) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if modulePath, err := strconv.Unquote(fields[1]); err == nil {
+			return modulePath, true
+		}
+		return fields[1], true
+	}
+	return "", true
+}
+
+// buildTarget returns the package to build, relative to the module
+// directory, given the import path of the tool and a package relative to it.
+// Import paths outside of the module, such as the URL of the repository, are
+// taken to refer to the module root.
+func (m module) buildTarget(importPath, pkg string) string {
+	var rel string
+	if m.Path != "" && (importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/")) {
+		rel = strings.TrimPrefix(importPath, m.Path)
+	}
+
+	target := path.Join(".", rel, pkg)
+	if target == "." || strings.HasPrefix(target, "../") {
+		return target
+	}
+	return "./" + target
+}
+
+// joinFlags appends flags to a GOFLAGS value.
+func joinFlags(goflags string, flags ...string) string {
+	return strings.TrimSpace(goflags + " " + strings.Join(flags, " "))
+}
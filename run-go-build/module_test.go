@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, file, content string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindModule(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	checkout := filepath.Join(tid.TestDir(), "checkout")
+	repo := filepath.Join(checkout, "src", "example.com", "tool")
+	writeFile(t, filepath.Join(repo, "go.mod"), "module example.com/tool/v2\n\ngo 1.16\n")
+	writeFile(t, filepath.Join(repo, "cmd", "tool", "main.go"), "package main\n")
+
+	// Checkouts by getters other than go-get hold the repository at the root
+	rootCheckout := filepath.Join(tid.TestDir(), "root")
+	writeFile(t, filepath.Join(rootCheckout, "go.mod"), "module example.com/tool/v2\n")
+
+	gopathCheckout := filepath.Join(tid.TestDir(), "gopath")
+	writeFile(t, filepath.Join(gopathCheckout, "src", "example.com", "old", "main.go"), "package main\n")
+
+	data := []struct {
+		Name       string
+		Checkout   string
+		ImportPath string
+		Package    string
+		ModuleDir  string
+		Target     string
+	}{
+		{"Root", checkout, "example.com/tool/v2", "", repo, "."},
+		{"Package", checkout, "example.com/tool/v2", "./cmd/tool", repo, "./cmd/tool"},
+		{"SubPackage", checkout, "example.com/tool/v2/cmd/tool", "", repo, "./cmd/tool"},
+		{"RepositoryURL", rootCheckout, "https://example.com/tool", "./cmd/tool", rootCheckout, "./cmd/tool"},
+		{"GOPATH", gopathCheckout, "example.com/old", "", "", ""},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			module, ok := findModule(d.Checkout, d.ImportPath)
+			assert.Equal(t, d.ModuleDir != "", ok)
+			if ok {
+				assert.Equal(t, d.ModuleDir, module.Dir)
+				assert.Equal(t, "example.com/tool/v2", module.Path)
+				assert.Equal(t, d.Target, module.buildTarget(d.ImportPath, d.Package))
+			}
+		})
+	}
+}
+
+func TestBinaryName(t *testing.T) {
+	assert.Equal(t, "tool", binaryName("example.com/tool"))
+	assert.Equal(t, "tool", binaryName("example.com/tool/v2"))
+	assert.Equal(t, "foo", binaryName("example.com/tool/v2/cmd/foo"))
+	assert.Equal(t, "v2", binaryName("v2"))
+}
+
+type testCheckout string
+
+func (tc testCheckout) Path() string          { return string(tc) }
+func (tc testCheckout) Version() tool.Version { return "v2.0.0" }
+
+func TestSetupModule(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	checkout := filepath.Join(tid.TestDir(), "checkout")
+	repo := filepath.Join(checkout, "src", "example.com", "tool")
+	writeFile(t, filepath.Join(repo, "go.mod"), "module example.com/tool/v2\n\ngo 1.16\n")
+	writeFile(t, filepath.Join(repo, "cmd", "tool", "main.go"), "package main\n\nfunc main() {}\n")
+
+	r := runner{
+		ShellRunner: shell.Runner{Options: shell.Options{
+			SetupEnvironment: map[string]string{},
+		}},
+		Options:      Options{Package: "./cmd/tool"},
+		ImportPath:   "example.com/tool/v2",
+		BuildEnviron: os.Environ(),
+		Binary:       filepath.Join("bin", "tool"),
+		GoRoot:       filepath.Join(tid.TestDir(), "go"),
+	}
+
+	assert.Nil(t, r.Setup(testCheckout(checkout)))
+
+	_, err := os.Stat(filepath.Join(checkout, "bin", "tool"))
+	assert.Nil(t, err)
+	assert.Equal(t, r.GoRoot, r.ShellRunner.Options.SetupEnvironment["GOPATH"])
+}
@@ -8,19 +8,28 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/stoic-cli/stoic-cli-core"
 	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
 	"github.com/stoic-cli/stoic-cli-core/tool"
 )
 
 func NewRunner(stoic stoic.Stoic, tool stoic.Tool) (tool.Runner, error) {
+	var options Options
+	err := mapstructure.Decode(tool.Config().Runner.Options, &options)
+	if err != nil {
+		return nil, err
+	}
+
 	importPath := tool.Config().Endpoint
 	buildEnviron := append(os.Environ(),
 		"GOARCH="+runtime.GOARCH,
 		"GOOS="+runtime.GOOS,
-		// TODO: set GOARM
 	)
-	binary := filepath.Join("bin", path.Base(importPath))
+	if goarm, _ := stoic.Parameters()["Arm"].(string); goarm != "" {
+		buildEnviron = append(buildEnviron, "GOARM="+goarm)
+	}
+	binary := filepath.Join("bin", binaryName(path.Join(importPath, options.Package)))
 
 	if _, ok := tool.Config().Runner.Options["command"]; !ok {
 		tool.Config().Runner.Options["command"] = binary
@@ -36,30 +45,65 @@ func NewRunner(stoic stoic.Stoic, tool stoic.Tool) (tool.Runner, error) {
 			"unable to cast shell runner of type %T to shell.Runner",
 			shellRunner)
 	}
-	return &runner{sr, importPath, buildEnviron, binary}, nil
+	return &runner{
+		ShellRunner:  sr,
+		Options:      options,
+		ImportPath:   importPath,
+		BuildEnviron: buildEnviron,
+		Binary:       binary,
+		GoRoot:       filepath.Join(stoic.Root(), "go"),
+	}, nil
+}
+
+type Options struct {
+	// Package is the path of the package to build, relative to the import
+	// path of the tool (e.g., ./cmd/foo). By default, the package at the
+	// import path is built.
+	Package string
 }
 
 type runner struct {
 	ShellRunner  shell.Runner
+	Options      Options
 	ImportPath   string
 	BuildEnviron []string
 	Binary       string
+
+	// GoRoot is the shared GOPATH for module-mode builds, under the stoic
+	// root, where the module cache is kept.
+	GoRoot string
 }
 
 func (r runner) Setup(checkout tool.Checkout) error {
 	gopath := checkout.Path()
 
-	build := exec.Command("go", "build", "-o", r.Binary, r.ImportPath)
-	build.Dir = gopath
-	build.Env = append(r.BuildEnviron, "GOPATH="+gopath)
+	build := exec.Command("go", "build", "-o", filepath.Join(gopath, r.Binary))
 	build.Stderr = os.Stderr
 	build.Stdout = os.Stderr
 
+	if module, ok := findModule(gopath, r.ImportPath); ok {
+		build.Args = append(build.Args, module.buildTarget(r.ImportPath, r.Options.Package))
+		build.Dir = module.Dir
+		build.Env = append(r.BuildEnviron,
+			"GO111MODULE=on",
+			"GOFLAGS="+joinFlags(os.Getenv("GOFLAGS"), "-mod=mod"),
+			"GOPATH="+r.GoRoot,
+			"GOMODCACHE="+filepath.Join(r.GoRoot, "pkg", "mod"),
+		)
+		r.ShellRunner.Options.SetupEnvironment["GOPATH"] = r.GoRoot
+	} else {
+		build.Args = append(build.Args, path.Join(r.ImportPath, r.Options.Package))
+		build.Dir = gopath
+		build.Env = append(r.BuildEnviron,
+			"GO111MODULE=off",
+			"GOPATH="+gopath,
+		)
+		r.ShellRunner.Options.SetupEnvironment["GOPATH"] = gopath
+	}
+
 	if err := build.Run(); err != nil {
 		return err
 	}
-
-	r.ShellRunner.Options.SetupEnvironment["GOPATH"] = gopath
 	return r.ShellRunner.Setup(checkout)
 }
 
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Package managers supported by the node runner.
+const (
+	NPM  = "npm"
+	PNPM = "pnpm"
+	Yarn = "yarn"
+)
+
+const packageJSONBase = "package.json"
+
+// lockfiles lists, in order of preference, the lockfiles of each package
+// manager.
+var lockfiles = []struct {
+	Base, PackageManager string
+}{
+	{"pnpm-lock.yaml", PNPM},
+	{"yarn.lock", Yarn},
+	{"npm-shrinkwrap.json", NPM},
+	{"package-lock.json", NPM},
+}
+
+// configFiles are copied from the package to environments, as they may
+// configure registries and credentials for them.
+var configFiles = []string{".npmrc", ".yarnrc", ".yarnrc.yml"}
+
+// findLockfile returns the path of the lockfile in packageDir for
+// packageManager, or for the first package manager with a lockfile there if
+// packageManager is empty.
+func findLockfile(packageDir, packageManager string) (string, string, error) {
+	for _, lockfile := range lockfiles {
+		if packageManager != "" && lockfile.PackageManager != packageManager {
+			continue
+		}
+		path := filepath.Join(packageDir, lockfile.Base)
+		if fileExists(path) {
+			return path, lockfile.PackageManager, nil
+		}
+	}
+
+	if packageManager == "" {
+		packageManager = "npm, pnpm or yarn"
+	}
+	return "", "", errors.Errorf("no %v lockfile found in %v", packageManager, packageDir)
+}
+
+// packageManifest holds the fields of package.json used by the runner.
+type packageManifest struct {
+	Name string          `json:"name"`
+	Bin  json.RawMessage `json:"bin"`
+}
+
+func readPackageManifest(packageDir string) (packageManifest, error) {
+	var manifest packageManifest
+
+	content, err := ioutil.ReadFile(filepath.Join(packageDir, packageJSONBase))
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(content, &manifest)
+	return manifest, errors.Wrapf(err, "unable to parse %v in %v", packageJSONBase, packageDir)
+}
+
+// bins returns the executables of the package, keyed by name. As with npm, a
+// single bin path is named after the package, without its scope.
+func (pm packageManifest) bins() (map[string]string, error) {
+	if len(pm.Bin) == 0 {
+		return nil, nil
+	}
+
+	var bin string
+	if err := json.Unmarshal(pm.Bin, &bin); err == nil {
+		name := pm.Name
+		if i := strings.LastIndexByte(name, '/'); i != -1 {
+			name = name[i+1:]
+		}
+		return map[string]string{name: bin}, nil
+	}
+
+	var bins map[string]string
+	err := json.Unmarshal(pm.Bin, &bins)
+	return bins, errors.Wrap(err, "invalid bin in package.json")
+}
+
+// selectBin returns the path, relative to the package, of the executable
+// named name, or of the tool if name is empty. It defaults to the only
+// executable of the package.
+func (pm packageManifest) selectBin(name, toolName string) (string, error) {
+	bins, err := pm.bins()
+	if err != nil {
+		return "", err
+	}
+
+	if name != "" {
+		if bin, ok := bins[name]; ok {
+			return bin, nil
+		}
+		return "", errors.Errorf("no bin named '%v' in package.json", name)
+	}
+
+	if bin, ok := bins[toolName]; ok {
+		return bin, nil
+	}
+	if len(bins) == 1 {
+		for _, bin := range bins {
+			return bin, nil
+		}
+	}
+
+	if len(bins) == 0 {
+		return "", errors.New("no bin in package.json, set bin or command")
+	}
+	var names []string
+	for name := range bins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", errors.Errorf(
+		"more than one bin in package.json, set bin to one of: %v",
+		strings.Join(names, ", "))
+}
+
+// environmentManifest returns the content of package.json for environments,
+// without the scripts of the package. Its sources are not part of
+// environments, only its dependencies are installed there.
+func environmentManifest(packageDir string) ([]byte, error) {
+	content, err := ioutil.ReadFile(filepath.Join(packageDir, packageJSONBase))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %v in %v", packageJSONBase, packageDir)
+	}
+	delete(manifest, "scripts")
+	delete(manifest, "bin")
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBin(t *testing.T) {
+	data := []struct {
+		Name     string
+		Manifest string
+		Bin      string
+		Expected string
+	}{
+		{"Single", `{"name": "tool", "bin": "cli.js"}`, "", "cli.js"},
+		{"Scoped", `{"name": "@team/tool", "bin": "cli.js"}`, "tool", "cli.js"},
+		{"OnlyEntry", `{"bin": {"other": "other.js"}}`, "", "other.js"},
+		{"ToolName", `{"bin": {"tool": "tool.js", "other": "other.js"}}`, "", "tool.js"},
+		{"Named", `{"bin": {"tool": "tool.js", "other": "other.js"}}`, "other", "other.js"},
+		{"Ambiguous", `{"bin": {"one": "one.js", "two": "two.js"}}`, "", ""},
+		{"Missing", `{"bin": {"tool": "tool.js"}}`, "other", ""},
+		{"None", `{"name": "tool"}`, "", ""},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			var manifest packageManifest
+			assert.Nil(t, json.Unmarshal([]byte(d.Manifest), &manifest))
+
+			bin, err := manifest.selectBin(d.Bin, "tool")
+			if d.Expected == "" {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, d.Expected, bin)
+		})
+	}
+}
+
+func TestFindLockfile(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	dir := tid.TestDir()
+	assert.Nil(t, ioutil.WriteFile("package-lock.json", nil, 0644))
+	assert.Nil(t, ioutil.WriteFile("yarn.lock", nil, 0644))
+
+	lockfile, packageManager, err := findLockfile(dir, "")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "yarn.lock"), lockfile)
+	assert.Equal(t, Yarn, packageManager)
+
+	lockfile, packageManager, err = findLockfile(dir, NPM)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "package-lock.json"), lockfile)
+	assert.Equal(t, NPM, packageManager)
+
+	_, _, err = findLockfile(dir, PNPM)
+	assert.NotNil(t, err)
+}
+
+func TestEnvironmentManifest(t *testing.T) {
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	assert.Nil(t, ioutil.WriteFile(packageJSONBase, []byte(`{
+		"name": "tool",
+		"bin": {"tool": "cli.js"},
+		"scripts": {"postinstall": "tsc"},
+		"dependencies": {"left-pad": "1.3.0"}
+	}`), 0644))
+
+	content, err := environmentManifest(tid.TestDir())
+	assert.Nil(t, err)
+
+	var manifest map[string]interface{}
+	assert.Nil(t, json.Unmarshal(content, &manifest))
+	assert.Equal(t, map[string]interface{}{
+		"name":         "tool",
+		"dependencies": map[string]interface{}{"left-pad": "1.3.0"},
+	}, manifest)
+}
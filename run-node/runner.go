@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core"
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+func NewRunner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
+	var options NodeOptions
+	err := mapstructure.Decode(t.Config().Runner.Options, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Node == "" {
+		options.Node = "node"
+	}
+	node, err := lookupAbsolutePath(options.Node)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"unable to find node executable %s: %v", options.Node, err)
+	}
+
+	switch options.PackageManager {
+	case "", NPM, PNPM, Yarn:
+	default:
+		return nil, errors.Errorf("unsupported package manager, '%v'", options.PackageManager)
+	}
+
+	if options.Bin != "" && options.Command != "" {
+		return nil, fmt.Errorf(
+			"bin and command cannot both be specified simultaneously\n"+
+				"\tbin: %v\n"+
+				"\tcommand: %v\n",
+			options.Bin, options.Command)
+	}
+
+	root := filepath.Join(s.Root(), "node")
+
+	shellRunner, err := shell.NewRunner(s, t)
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := shellRunner.(shell.Runner)
+	if !ok {
+		return nil, fmt.Errorf(
+			"unable to cast shell runner of type %T to shell.Runner",
+			shellRunner)
+	}
+	return runner{sr, root, node, t.Name(), options}, nil
+}
+
+type NodeOptions struct {
+	// Node is the node executable, looked up in PATH by default.
+	Node string `mapstructure:",omitempty"`
+
+	// PackageManager installs dependencies, one of npm, pnpm or yarn. By
+	// default, it is picked according to the lockfile of the package.
+	PackageManager string `mapstructure:"package-manager,omitempty"`
+
+	// PackageDir is the directory of package.json in the checkout.
+	PackageDir string `mapstructure:"package-dir,omitempty"`
+
+	// Bin is the name of the executable to run, out of bin entries in
+	// package.json. It defaults to the one named after the tool, or the only
+	// one.
+	Bin string `mapstructure:",omitempty"`
+
+	Command string `mapstructure:",omitempty"`
+}
+
+type runner struct {
+	ShellRunner shell.Runner
+
+	Root     string
+	Node     string
+	ToolName string
+
+	NodeOptions
+}
+
+func (r runner) packageDir(checkout tool.Checkout) string {
+	return filepath.Join(checkout.Path(), r.PackageDir)
+}
+
+// nodeEnv returns the environment for the package in checkout, which may not
+// be set up yet.
+func (r runner) nodeEnv(checkout tool.Checkout) (*nodeEnv, string, error) {
+	lockfile, packageManager, err := findLockfile(r.packageDir(checkout), r.PackageManager)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	version, err := cachedNodeVersion(r.Root, r.Node)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &nodeEnv{
+		root:           environmentRoot(nodeEnvRoot(r.Root, r.Node, version), packageManager, content),
+		node:           r.Node,
+		packageManager: packageManager,
+		cache:          filepath.Join(r.Root, "cache"),
+	}, lockfile, nil
+}
+
+func (r runner) setupNodeEnv(checkout tool.Checkout) (*nodeEnv, error) {
+	ne, lockfile, err := r.nodeEnv(checkout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setupNodeEnv(ne, r.packageDir(checkout), lockfile); err != nil {
+		return nil, err
+	}
+	return ne, linkNodeModules(ne, r.packageDir(checkout))
+}
+
+func (r runner) Setup(checkout tool.Checkout) error {
+	ne, err := r.setupNodeEnv(checkout)
+	if err != nil {
+		return err
+	}
+
+	r.ShellRunner.Options.SetupParameters["Node"] = r.Node
+	r.ShellRunner.Options.SetupEnvironment["PATH"] = ne.EnvPath()
+	return r.ShellRunner.Setup(checkout)
+}
+
+func (r runner) SharedResources() ([]string, error) {
+	return util.GlobSharedResources(filepath.Join(r.Root, "*", "env", "*", "*"))
+}
+
+func (r runner) SharedResourcesFor(checkout tool.Checkout) ([]string, error) {
+	ne, _, err := r.nodeEnv(checkout)
+	if err != nil {
+		return nil, err
+	}
+	return []string{ne.root}, nil
+}
+
+func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
+	ne, err := r.setupNodeEnv(checkout)
+	if err != nil {
+		return err
+	}
+
+	if r.Command == "" {
+		manifest, err := readPackageManifest(r.packageDir(checkout))
+		if err != nil {
+			return err
+		}
+		bin, err := manifest.selectBin(r.Bin, r.ToolName)
+		if err != nil {
+			return err
+		}
+
+		r.ShellRunner.Options.Parameters["Bin"] = filepath.Join(r.packageDir(checkout), bin)
+		r.ShellRunner.Options.Command = "{{.Node}} {{.Bin}}"
+	}
+
+	r.ShellRunner.Options.Parameters["Node"] = r.Node
+	r.ShellRunner.Options.Parameters["NodeModules"] = ne.NodeModules()
+
+	r.ShellRunner.Options.Environment["PATH"] = ne.EnvPath()
+	return r.ShellRunner.Run(checkout, name, args)
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStoic struct {
+	stoic.Stoic
+}
+
+func (ts testStoic) Parameters() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+type testCheckout string
+
+func (tc testCheckout) Path() string          { return string(tc) }
+func (tc testCheckout) Version() tool.Version { return "v1.0.0" }
+
+const packageLock = `{
+  "name": "tool",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {
+    "": {
+      "name": "tool",
+      "version": "1.0.0"
+    }
+  }
+}
+`
+
+func TestRunner(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm is not available")
+	}
+	node, err := lookupAbsolutePath("node")
+	if err != nil {
+		t.Skip("node is not available")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	checkout := filepath.Join(tid.TestDir(), "checkout")
+	assert.Nil(t, os.Mkdir(checkout, 0755))
+	for file, content := range map[string]string{
+		"package.json":      `{"name": "tool", "version": "1.0.0", "bin": {"tool": "cli.js"}}`,
+		"package-lock.json": packageLock,
+		"cli.js":            "require('fs').writeFileSync('args', JSON.stringify(process.argv.slice(2)))\n",
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(checkout, file), []byte(content), 0644))
+	}
+
+	r := runner{
+		ShellRunner: shell.Runner{
+			Stoic: testStoic{},
+			Options: shell.Options{
+				SetupEnvironment: map[string]string{},
+				SetupParameters:  map[string]interface{}{},
+				Environment:      map[string]string{},
+				Parameters:       map[string]interface{}{},
+				ChildProcess:     true,
+			},
+		},
+		Root:     filepath.Join(tid.TestDir(), "node"),
+		Node:     node,
+		ToolName: "tool",
+	}
+
+	assert.Nil(t, r.Setup(testCheckout(checkout)))
+
+	resources, err := r.SharedResourcesFor(testCheckout(checkout))
+	assert.Nil(t, err)
+	if assert.Len(t, resources, 1) {
+		_, err = os.Stat(filepath.Join(resources[0], readyBase))
+		assert.Nil(t, err)
+
+		target, err := os.Readlink(filepath.Join(checkout, nodeModulesBase))
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(resources[0], nodeModulesBase), target)
+	}
+
+	all, err := r.SharedResources()
+	assert.Nil(t, err)
+	assert.Equal(t, resources, all)
+
+	assert.Nil(t, r.Run(testCheckout(checkout), "tool", []string{"--flag", "arg"}))
+	args, err := ioutil.ReadFile("args")
+	assert.Nil(t, err)
+	assert.Equal(t, `["--flag","arg"]`, string(args))
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+const (
+	readyBase       = ".ready"
+	nodeModulesBase = "node_modules"
+	versionsBase    = "versions"
+)
+
+// nodeEnv is an environment with the dependencies of a package installed,
+// outside of checkouts.
+type nodeEnv struct {
+	root           string
+	node           string
+	packageManager string
+	cache          string
+}
+
+// NodeModules returns the path where dependencies are installed.
+func (ne *nodeEnv) NodeModules() string {
+	return filepath.Join(ne.root, nodeModulesBase)
+}
+
+// EnvPath returns the PATH environment variable setup for the environment,
+// with executables of dependencies and node itself.
+func (ne *nodeEnv) EnvPath() string {
+	envPath := filepath.Join(ne.NodeModules(), ".bin") +
+		string(os.PathListSeparator) + filepath.Dir(ne.node)
+	if curPath := os.Getenv("PATH"); curPath != "" {
+		envPath = envPath + string(os.PathListSeparator) + curPath
+	}
+	return envPath
+}
+
+// environForInstall returns os.Environ(), adjusted for the package manager to
+// use node and the shared package cache.
+func (ne *nodeEnv) environForInstall() []string {
+	environ := append(os.Environ(),
+		"PATH="+filepath.Dir(ne.node)+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	switch ne.packageManager {
+	case NPM:
+		environ = append(environ,
+			"npm_config_cache="+filepath.Join(ne.cache, NPM),
+			"npm_config_update_notifier=false")
+	case Yarn:
+		environ = append(environ, "YARN_CACHE_FOLDER="+filepath.Join(ne.cache, Yarn))
+	}
+	return environ
+}
+
+// installCommand returns the command that installs dependencies, exactly as
+// locked.
+func (ne *nodeEnv) installCommand() (*exec.Cmd, error) {
+	// Prefer the package manager that comes with node
+	packageManager := filepath.Join(filepath.Dir(ne.node), ne.packageManager)
+	if !fileExists(packageManager) {
+		var err error
+		packageManager, err = lookupAbsolutePath(ne.packageManager)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find %v", ne.packageManager)
+		}
+	}
+
+	var args []string
+	switch ne.packageManager {
+	case NPM:
+		args = []string{"ci", "--no-audit", "--no-fund"}
+	case PNPM:
+		args = []string{"install", "--frozen-lockfile",
+			"--store-dir", filepath.Join(ne.cache, PNPM)}
+	case Yarn:
+		args = []string{"install", "--frozen-lockfile", "--non-interactive"}
+	}
+
+	cmd := exec.Command(packageManager, args...)
+	cmd.Dir = ne.root
+	cmd.Env = ne.environForInstall()
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+// nodeVersion returns the version of node, as reported by node itself.
+func nodeVersion(node string) (string, error) {
+	output, err := exec.Command(node, "--version").Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get version of %v", node)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// cachedNodeVersion returns the version of the node executable, which is only
+// detected again when the executable changes.
+func cachedNodeVersion(root string, node string) (string, error) {
+	return util.CachedVersion(filepath.Join(root, versionsBase), node, nodeVersion)
+}
+
+// nodeEnvRoot returns the base path of environments set up with the given
+// version of node. Native dependencies are built for a specific version.
+func nodeEnvRoot(root, node, version string) string {
+	nodeHash := sha256.Sum256([]byte(node + "\n" + version))
+	return filepath.Join(root, fmt.Sprintf("%s-%.4x", filepath.Base(node), nodeHash))
+}
+
+// environmentRoot returns the base path of the environment for lockfile,
+// installed with packageManager, within envRoot.
+func environmentRoot(envRoot, packageManager string, lockfile []byte) string {
+	envHash := fmt.Sprintf("%x",
+		sha256.Sum256(append([]byte(packageManager+"\n"), lockfile...)))
+	return filepath.Join(envRoot, "env", envHash[:2], envHash[2:])
+}
+
+// setupNodeEnv sets up the environment for the package in packageDir, if it
+// isn't ready already.
+func setupNodeEnv(ne *nodeEnv, packageDir, lockfile string) error {
+	// Environments are shared by tools across stoic processes
+	envLock, err := util.LockSharedResource(ne.root)
+	if err != nil {
+		return err
+	}
+	defer envLock.Unlock()
+
+	marker := filepath.Join(ne.root, readyBase)
+	if fileExists(marker) {
+		util.TouchSharedResource(ne.root)
+		return nil
+	}
+
+	err = os.MkdirAll(ne.root, os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "unable to setup directory for node environment")
+	}
+
+	defer func() {
+		if err != nil {
+			os.RemoveAll(ne.root)
+		}
+	}()
+
+	manifest, err := environmentManifest(packageDir)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(ne.root, packageJSONBase), manifest, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to write package.json in node environment")
+	}
+
+	for _, file := range append([]string{filepath.Base(lockfile)}, configFiles...) {
+		var content []byte
+		content, err = ioutil.ReadFile(filepath.Join(packageDir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(ne.root, file), content, 0600)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "unable to copy %v to node environment", file)
+		}
+	}
+
+	install, err := ne.installCommand()
+	if err != nil {
+		return err
+	}
+	err = install.Run()
+	if err != nil {
+		return errors.Wrapf(err,
+			"unable to install dependencies in node environment with %v", ne.packageManager)
+	}
+
+	if err := ioutil.WriteFile(marker, currentTimestamp(), 0644); err != nil {
+		jww.DEBUG.Printf("failed to mark node environment as ready: %v", err)
+	}
+	return nil
+}
+
+// linkNodeModules links node_modules in packageDir to the dependencies
+// installed in the environment, where node looks them up.
+func linkNodeModules(ne *nodeEnv, packageDir string) error {
+	link := filepath.Join(packageDir, nodeModulesBase)
+
+	if info, err := os.Lstat(link); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return errors.Errorf("%v already exists in %v", nodeModulesBase, packageDir)
+		}
+		if target, _ := os.Readlink(link); target == ne.NodeModules() {
+			return nil
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(ne.NodeModules(), link)
+}
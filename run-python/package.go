@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+const (
+	pyprojectBase = "pyproject.toml"
+
+	// packageTargetBase is the directory in a checkout where the checkout
+	// itself is installed as a package.
+	packageTargetBase = ".python-package"
+)
+
+// lockfiles lists, in order of preference, the lockfiles looked up in a
+// checkout when no requirements file is configured, along with the command
+// exporting them as pip requirements.
+var lockfiles = []struct {
+	Base   string
+	Export []string
+}{
+	{"uv.lock", []string{"uv", "export", "--quiet", "--frozen", "--no-header",
+		"--no-emit-project", "--format", "requirements-txt"}},
+	{"poetry.lock", []string{"poetry", "export", "--format", "requirements.txt"}},
+	{"requirements.txt", nil},
+}
+
+// findLockfile returns the path to requirementsFile in checkoutDir, or to the
+// first lockfile found there, if requirementsFile is empty. It returns an
+// empty path if the checkout has no lockfile.
+func findLockfile(checkoutDir, requirementsFile string) (string, error) {
+	if requirementsFile != "" {
+		path := filepath.Join(checkoutDir, requirementsFile)
+		if !fileExists(path) {
+			return "", errors.Errorf("requirements file %v not found", path)
+		}
+		return path, nil
+	}
+
+	for _, lockfile := range lockfiles {
+		path := filepath.Join(checkoutDir, lockfile.Base)
+		if fileExists(path) {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// readLockfile returns the contents of lockfile, which key the virtual
+// environment. An empty path has no contents.
+func readLockfile(lockfile string) ([]byte, error) {
+	if lockfile == "" {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(lockfile)
+	return content, errors.Wrapf(err,
+		"unable to read requirements for virtual environment from %v", lockfile)
+}
+
+// exportRequirements returns pip requirements for lockfile, exporting them
+// with the tool that manages the lockfile, if needed.
+func exportRequirements(lockfile string, content []byte) ([]byte, error) {
+	for _, lf := range lockfiles {
+		if lf.Export == nil || filepath.Base(lockfile) != lf.Base {
+			continue
+		}
+
+		if _, err := exec.LookPath(lf.Export[0]); err != nil {
+			return nil, errors.Wrapf(err,
+				"%v is required to install requirements from %v",
+				lf.Export[0], lockfile)
+		}
+
+		cmd := exec.Command(lf.Export[0], lf.Export[1:]...)
+		cmd.Dir = filepath.Dir(lockfile)
+		cmd.Stderr = os.Stderr
+
+		requirements, err := cmd.Output()
+		return requirements, errors.Wrapf(err,
+			"unable to export requirements from %v", lockfile)
+	}
+	return content, nil
+}
+
+// pythonPackage holds the fields of pyproject.toml used by the runner.
+type pythonPackage struct {
+	Name string
+
+	// Scripts maps the names of console scripts to their entry points.
+	Scripts map[string]string
+}
+
+// readPyproject reads the package metadata from pyproject.toml in
+// checkoutDir. It returns nil if the checkout has no pyproject.toml, or if it
+// doesn't declare a package.
+func readPyproject(checkoutDir string) (*pythonPackage, error) {
+	path := filepath.Join(checkoutDir, pyprojectBase)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := toml.LoadBytes(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %v", path)
+	}
+
+	for _, table := range []string{"project", "tool.poetry"} {
+		project, ok := tree.Get(table).(*toml.Tree)
+		if !ok {
+			continue
+		}
+
+		pkg := &pythonPackage{Scripts: map[string]string{}}
+		pkg.Name, _ = project.Get("name").(string)
+
+		scripts, _ := project.Get("scripts").(*toml.Tree)
+		if scripts != nil {
+			for _, name := range scripts.Keys() {
+				entryPoint, ok := scripts.GetPath([]string{name}).(string)
+				if !ok {
+					jww.DEBUG.Printf(
+						"ignoring script %v in %v, as it isn't an entry point",
+						name, path)
+					continue
+				}
+				pkg.Scripts[name] = entryPoint
+			}
+		}
+		return pkg, nil
+	}
+	return nil, nil
+}
+
+// selectScript returns the name of the console script named name, or of the
+// tool if name is empty. It defaults to the only script of the package.
+func (pkg *pythonPackage) selectScript(name, toolName string) (string, error) {
+	if name != "" {
+		if _, ok := pkg.Scripts[name]; ok {
+			return name, nil
+		}
+		return "", errors.Errorf("no script named '%v' in %v", name, pyprojectBase)
+	}
+
+	if _, ok := pkg.Scripts[toolName]; ok {
+		return toolName, nil
+	}
+	if len(pkg.Scripts) == 1 {
+		for script := range pkg.Scripts {
+			return script, nil
+		}
+	}
+
+	if len(pkg.Scripts) == 0 {
+		return "", errors.Errorf("no scripts in %v", pyprojectBase)
+	}
+
+	var names []string
+	for script := range pkg.Scripts {
+		names = append(names, script)
+	}
+	sort.Strings(names)
+	return "", errors.Errorf(
+		"no script named '%v' in %v, pick one of: %v",
+		toolName, pyprojectBase, strings.Join(names, ", "))
+}
+
+// packageTarget returns the directory the checkout at checkoutDir is
+// installed to.
+func packageTarget(checkoutDir string) string {
+	return filepath.Join(checkoutDir, packageTargetBase)
+}
+
+// findScript returns the path to the console script named name, as installed
+// by pip in target.
+func findScript(target, name string) (string, error) {
+	for _, script := range []string{
+		filepath.Join(target, "bin", name),
+		filepath.Join(target, "bin", name+".exe"),
+		filepath.Join(target, "Scripts", name+".exe"),
+	} {
+		if fileExists(script) {
+			return script, nil
+		}
+	}
+	return "", errors.Errorf("script %v not installed in %v", name, target)
+}
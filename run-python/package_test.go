@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindLockfile(t *testing.T) {
+	data := []struct {
+		Name             string
+		Files            []string
+		RequirementsFile string
+		Lockfile         string
+		Error            bool
+	}{
+		{"None", nil, "", "", false},
+		{"Requirements", []string{"requirements.txt"}, "", "requirements.txt", false},
+		{"UV", []string{"requirements.txt", "uv.lock"}, "", "uv.lock", false},
+		{"Poetry", []string{"requirements.txt", "poetry.lock"}, "", "poetry.lock", false},
+		{"Configured", []string{"uv.lock", "requirements-dev.txt"}, "requirements-dev.txt", "requirements-dev.txt", false},
+		{"MissingConfigured", []string{"uv.lock"}, "requirements-dev.txt", "", true},
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			checkout := filepath.Join(tid.TestDir(), d.Name)
+			assert.Nil(t, os.Mkdir(checkout, 0755))
+			for _, file := range d.Files {
+				assert.Nil(t, ioutil.WriteFile(filepath.Join(checkout, file), nil, 0644))
+			}
+
+			lockfile, err := findLockfile(checkout, d.RequirementsFile)
+			if d.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if d.Lockfile == "" {
+				assert.Equal(t, "", lockfile)
+			} else {
+				assert.Equal(t, filepath.Join(checkout, d.Lockfile), lockfile)
+			}
+		})
+	}
+}
+
+func TestReadPyproject(t *testing.T) {
+	data := []struct {
+		Name      string
+		Pyproject string
+		Package   *pythonPackage
+	}{
+		{"Missing", "", nil},
+		{"NoPackage", "[tool.black]\nline-length = 100\n", nil},
+		{"Project", `
+[build-system]
+requires = ["hatchling"]
+build-backend = "hatchling.build"
+
+[project]
+name = "tool"
+version = "1.0.0"
+dependencies = [
+    "requests>=2",
+]
+
+[project.scripts]
+tool = "tool.cli:main"
+tool-admin = "tool.admin:main [admin]"
+`, &pythonPackage{"tool", map[string]string{
+			"tool":       "tool.cli:main",
+			"tool-admin": "tool.admin:main [admin]",
+		}}},
+		{"Poetry", `
+[tool.poetry]
+name = "tool"
+version = "1.0.0"
+
+[tool.poetry.scripts]
+tool = "tool.cli:main"
+other = { reference = "other.sh", type = "file" }
+`, &pythonPackage{"tool", map[string]string{"tool": "tool.cli:main"}}},
+		{"NoScripts", "[project]\nname = \"tool\"\n",
+			&pythonPackage{"tool", map[string]string{}}},
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			checkout := filepath.Join(tid.TestDir(), d.Name)
+			assert.Nil(t, os.Mkdir(checkout, 0755))
+			if d.Pyproject != "" {
+				assert.Nil(t, ioutil.WriteFile(
+					filepath.Join(checkout, pyprojectBase), []byte(d.Pyproject), 0644))
+			}
+
+			pkg, err := readPyproject(checkout)
+			assert.Nil(t, err)
+			assert.Equal(t, d.Package, pkg)
+		})
+	}
+}
+
+func TestSelectScript(t *testing.T) {
+	single := &pythonPackage{"tool", map[string]string{"cli": "tool.cli:main"}}
+	multiple := &pythonPackage{"tool", map[string]string{
+		"tool":  "tool.cli:main",
+		"admin": "tool.admin:main",
+	}}
+
+	data := []struct {
+		Name    string
+		Package *pythonPackage
+		Script  string
+		Result  string
+		Error   bool
+	}{
+		{"Single", single, "", "cli", false},
+		{"ToolName", multiple, "", "tool", false},
+		{"Named", multiple, "admin", "admin", false},
+		{"MissingNamed", multiple, "other", "", true},
+		{"Ambiguous", &pythonPackage{"tool", map[string]string{"a": "a:main", "b": "b:main"}}, "", "", true},
+		{"NoScripts", &pythonPackage{"tool", map[string]string{}}, "", "", true},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			script, err := d.Package.selectScript(d.Script, "tool")
+			if d.Error {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, d.Result, script)
+		})
+	}
+}
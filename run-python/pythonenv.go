@@ -2,11 +2,12 @@ package runner
 
 import (
 	"os"
+	"strings"
 )
 
-// PythonEnv represents the base Python setup used by the Runner. It hosts an
-// isolated setup of pip, wheel, and virtualenv, and can be used to setup
-// virtual environments.
+// PythonEnv represents the base Python setup used by the Runner. For Python 2,
+// it hosts an isolated setup of pip, wheel, and virtualenv, and can be used to
+// setup virtual environments.
 type PythonEnv interface {
 	// Root returns the base path of the environment.
 	Root() string
@@ -29,16 +30,27 @@ type PythonEnv interface {
 
 	// Environ returns os.Environ(), adjusted with this environment's settings.
 	Environ() []string
+
+	// UsesVenv is true if virtual environments are set up with the venv
+	// module of the standard library, with pip installed by ensurepip. The
+	// environment doesn't host a setup of its own then.
+	UsesVenv() bool
+
+	// Toolchain returns requirements for pip and related packages, which are
+	// installed in virtual environments if set.
+	Toolchain() []string
 }
 
-func newPythonEnv(envRoot string, python string, pipCache string) PythonEnv {
-	return &pythonEnv{envRoot, python, pipCache}
+func newPythonEnv(envRoot string, python string, pipCache string, version string, toolchain []string) PythonEnv {
+	return &pythonEnv{envRoot, python, pipCache, version, toolchain}
 }
 
 type pythonEnv struct {
-	root     string
-	python   string
-	pipCache string
+	root      string
+	python    string
+	pipCache  string
+	version   string
+	toolchain []string
 }
 
 func (pe *pythonEnv) PipCache() string {
@@ -54,8 +66,17 @@ func (pe *pythonEnv) Root() string {
 }
 
 func (pe *pythonEnv) Environ() []string {
-	return append(os.Environ(),
-		"PIP_CACHE_DIR="+pe.pipCache,
-		"PYTHONPATH="+pe.SitePackages(),
-	)
+	environ := append(os.Environ(), "PIP_CACHE_DIR="+pe.pipCache)
+	if !pe.UsesVenv() {
+		environ = append(environ, "PYTHONPATH="+pe.SitePackages())
+	}
+	return environ
+}
+
+func (pe *pythonEnv) UsesVenv() bool {
+	return !strings.HasPrefix(pe.version, "2.")
+}
+
+func (pe *pythonEnv) Toolchain() []string {
+	return pe.toolchain
 }
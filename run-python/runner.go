@@ -12,6 +12,7 @@ import (
 	"github.com/stoic-cli/stoic-cli-core"
 	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
 	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
 )
 
 func newRunner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
@@ -72,7 +73,7 @@ func newRunner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
 			"unable to cast shell runner of type %T to shell.Runner",
 			shellRunner)
 	}
-	return runner{sr, root, absolutePython, options}, nil
+	return runner{sr, root, absolutePython, t.Name(), options}, nil
 }
 
 func NewPythonRunner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
@@ -94,30 +95,97 @@ func NewPython3Runner(s stoic.Stoic, t stoic.Tool) (tool.Runner, error) {
 }
 
 type PythonOptions struct {
-	Python           string `mapstructure:",omitempty"`
+	Python string `mapstructure:",omitempty"`
+
+	// RequirementsFile is the pip requirements file for the virtual
+	// environment. It defaults to the first of uv.lock, poetry.lock, or
+	// requirements.txt in the checkout. uv.lock and poetry.lock are exported
+	// with uv and poetry, respectively.
 	RequirementsFile string `mapstructure:"requirements,omitempty"`
-	ModulePath       string `mapstructure:"module-path,omitempty"`
-	EntryPoint       string `mapstructure:"entry-point,omitempty"`
-	Command          string `mapstructure:",omitempty"`
+
+	// InstallPackage controls whether the checkout is installed as a package,
+	// when it has a pyproject.toml. It defaults to true. Dependencies of the
+	// package are only installed along with it if the checkout has no
+	// requirements.
+	InstallPackage *bool `mapstructure:"install-package,omitempty"`
+
+	// Script is the name of the console script to run, out of scripts in
+	// pyproject.toml. It defaults to the one named after the tool, or the
+	// only one.
+	Script string `mapstructure:",omitempty"`
+
+	ModulePath string `mapstructure:"module-path,omitempty"`
+	EntryPoint string `mapstructure:"entry-point,omitempty"`
+	Command    string `mapstructure:",omitempty"`
+
+	// Toolchain lists requirements for pip and related packages (e.g.,
+	// pip==24.0). By default, Python 3 environments use the pip bundled with
+	// the interpreter, and Python 2 environments use pinned versions of pip,
+	// setuptools, virtualenv, and wheel.
+	Toolchain []string `mapstructure:"toolchain,omitempty"`
 }
 
 type runner struct {
 	ShellRunner shell.Runner
 
-	Root   string
-	Python string
+	Root     string
+	Python   string
+	ToolName string
 
 	PythonOptions
 }
 
-func (r runner) Setup(checkout tool.Checkout) error {
-	pe, err := setupPythonEnv(r.Root, r.Python, r.ShellRunner.Stoic.Cache())
+// setupEnv sets up the virtual environment for checkout and, if enabled,
+// installs the checkout as a package. The package is nil if it isn't
+// installed.
+func (r runner) setupEnv(checkout tool.Checkout) (PythonEnv, VirtualEnv, *pythonPackage, error) {
+	pe, err := setupPythonEnv(
+		r.Root, r.Python, r.Toolchain, r.ShellRunner.Stoic.Cache())
 	if err != nil {
-		return err
+		return nil, nil, nil, err
+	}
+
+	lockfile, err := findLockfile(checkout.Path(), r.RequirementsFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ve, err := setupVirtualEnv(pe, lockfile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if r.InstallPackage != nil && !*r.InstallPackage {
+		return pe, ve, nil, nil
 	}
+	pkg, err := readPyproject(checkout.Path())
+	if err != nil || pkg == nil {
+		return pe, ve, nil, err
+	}
+	_, err = setupPackage(ve, checkout.Path(), lockfile == "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pe, ve, pkg, nil
+}
+
+// pythonPath returns the PYTHONPATH for running in checkout, given the
+// package installed in it, if any.
+func (r runner) pythonPath(checkout tool.Checkout, pe PythonEnv, pkg *pythonPackage) string {
+	var pythonPath []string
+	if r.ModulePath != "" {
+		pythonPath = append(pythonPath, filepath.Join(checkout.Path(), r.ModulePath))
+	}
+	if pkg != nil {
+		pythonPath = append(pythonPath, packageTarget(checkout.Path()))
+	}
+	if !pe.UsesVenv() {
+		pythonPath = append(pythonPath, pe.SitePackages())
+	}
+	return strings.Join(pythonPath, string(os.PathListSeparator))
+}
 
-	requirementsFile := filepath.Join(checkout.Path(), r.RequirementsFile)
-	ve, err := setupVirtualEnv(pe, requirementsFile)
+func (r runner) Setup(checkout tool.Checkout) error {
+	pe, ve, pkg, err := r.setupEnv(checkout)
 	if err != nil {
 		return err
 	}
@@ -127,7 +195,8 @@ func (r runner) Setup(checkout tool.Checkout) error {
 
 	setupCommand.Stdout = os.Stderr
 	setupCommand.Stderr = os.Stderr
-	setupCommand.Env = ve.Environ()
+	setupCommand.Env = append(ve.Environ(),
+		"PYTHONPATH="+r.pythonPath(checkout, pe, pkg))
 
 	err = setupCommand.Run()
 	if err != nil {
@@ -135,18 +204,37 @@ func (r runner) Setup(checkout tool.Checkout) error {
 	}
 
 	r.ShellRunner.Options.SetupEnvironment["PIP_CACHE_DIR"] = pe.PipCache()
-	r.ShellRunner.Options.SetupEnvironment["PYTHONPATH"] = pe.SitePackages()
+	if !pe.UsesVenv() {
+		r.ShellRunner.Options.SetupEnvironment["PYTHONPATH"] = pe.SitePackages()
+	}
 	return r.ShellRunner.Setup(checkout)
 }
 
-func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
-	pe, err := setupPythonEnv(r.Root, r.Python, r.ShellRunner.Stoic.Cache())
+func (r runner) SharedResources() ([]string, error) {
+	return util.GlobSharedResources(filepath.Join(r.Root, "*", "env", "*", "*"))
+}
+
+func (r runner) SharedResourcesFor(checkout tool.Checkout) ([]string, error) {
+	lockfile, err := findLockfile(checkout.Path(), r.RequirementsFile)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	lock, err := readLockfile(lockfile)
+	if err != nil {
+		return nil, err
 	}
 
-	requirementsFile := filepath.Join(checkout.Path(), r.RequirementsFile)
-	ve, err := setupVirtualEnv(pe, requirementsFile)
+	version, err := cachedPythonVersion(r.Root, r.Python)
+	if err != nil {
+		return nil, err
+	}
+
+	envRoot := pythonEnvRoot(r.Root, r.Python, version, r.Toolchain)
+	return []string{virtualEnvRoot(envRoot, lock)}, nil
+}
+
+func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
+	pe, ve, pkg, err := r.setupEnv(checkout)
 	if err != nil {
 		return err
 	}
@@ -166,12 +254,18 @@ func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
 
 			"s.exit(e());" +
 			"\" {{.EntryPoint}}"
-	}
+	} else if r.Command == "" && pkg != nil {
+		scriptName, err := pkg.selectScript(r.Script, r.ToolName)
+		if err != nil {
+			return err
+		}
+		script, err := findScript(packageTarget(checkout.Path()), scriptName)
+		if err != nil {
+			return err
+		}
 
-	pythonPath := pe.SitePackages()
-	if r.ModulePath != "" {
-		modulePath := filepath.Join(checkout.Path(), r.ModulePath)
-		pythonPath = modulePath + string(os.PathListSeparator) + pythonPath
+		r.ShellRunner.Options.Parameters["Script"] = script
+		r.ShellRunner.Options.Command = "{{.Script}}"
 	}
 
 	r.ShellRunner.Options.Parameters["Python"] = ve.Python()
@@ -181,7 +275,7 @@ func (r runner) Run(checkout tool.Checkout, name string, args []string) error {
 	// TODO: Should filter out PYTHONHOME from environment, if set
 
 	r.ShellRunner.Options.Environment["PATH"] = ve.EnvPath()
-	r.ShellRunner.Options.Environment["PYTHONPATH"] = pythonPath
+	r.ShellRunner.Options.Environment["PYTHONPATH"] = r.pythonPath(checkout, pe, pkg)
 	r.ShellRunner.Options.Environment["VIRTUAL_ENV"] = ve.Root()
 	return r.ShellRunner.Run(checkout, name, args)
 }
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stoic-cli/stoic-cli-core"
+	shell "github.com/stoic-cli/stoic-cli-core/run-shell"
+	"github.com/stoic-cli/stoic-cli-core/tool"
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStoic struct {
+	stoic.Stoic
+}
+
+func (ts testStoic) Cache() stoic.Cache { return nil }
+
+func (ts testStoic) Parameters() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+type testCheckout string
+
+func (tc testCheckout) Path() string          { return string(tc) }
+func (tc testCheckout) Version() tool.Version { return "v1.0.0" }
+
+func TestPythonEnvRoot(t *testing.T) {
+	root := pythonEnvRoot("root", "/usr/bin/python3", "3.11.7", nil)
+	assert.True(t, strings.HasPrefix(root, filepath.Join("root", "python3-")))
+
+	assert.Equal(t, root, pythonEnvRoot("root", "/usr/bin/python3", "3.11.7", nil))
+	assert.NotEqual(t, root, pythonEnvRoot("root", "/usr/bin/python3", "3.12.0", nil))
+	assert.NotEqual(t, root, pythonEnvRoot("root", "/usr/bin/python3", "3.11.7",
+		[]string{"pip==24.0"}))
+}
+
+func TestRunnerVenv(t *testing.T) {
+	python, err := lookupAbsolutePath("python3")
+	if err != nil {
+		t.Skip("python3 is not available")
+	}
+	if version, err := pythonVersion(python); err != nil || strings.HasPrefix(version, "2.") {
+		t.Skip("python3 is not usable")
+	}
+
+	tid := util.SetupTestInDir(t)
+	defer tid.Close()
+
+	checkout := filepath.Join(tid.TestDir(), "checkout")
+	assert.Nil(t, os.Mkdir(checkout, 0755))
+	for file, content := range map[string]string{
+		"requirements.txt": "",
+		"tool.py": "import sys\n" +
+			"def main():\n" +
+			"    open('args', 'w').write(' '.join([sys.prefix] + sys.argv[1:]))\n",
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(checkout, file), []byte(content), 0644))
+	}
+
+	r := runner{
+		ShellRunner: shell.Runner{
+			Stoic: testStoic{},
+			Options: shell.Options{
+				SetupEnvironment: map[string]string{},
+				SetupParameters:  map[string]interface{}{},
+				Environment:      map[string]string{},
+				Parameters:       map[string]interface{}{},
+				ChildProcess:     true,
+			},
+		},
+		Root:   filepath.Join(tid.TestDir(), "python"),
+		Python: python,
+		PythonOptions: PythonOptions{
+			RequirementsFile: "requirements.txt",
+			ModulePath:       ".",
+			EntryPoint:       "tool=tool:main",
+		},
+	}
+
+	assert.Nil(t, r.Setup(testCheckout(checkout)))
+
+	resources, err := r.SharedResourcesFor(testCheckout(checkout))
+	assert.Nil(t, err)
+	if !assert.Len(t, resources, 1) {
+		return
+	}
+	_, err = os.Stat(filepath.Join(resources[0], readyBase))
+	assert.Nil(t, err)
+
+	// No setup of pip outside of the virtual environment
+	_, err = os.Stat(filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(resources[0]))), "lib"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, r.Run(testCheckout(checkout), "tool", []string{"--flag", "arg"}))
+	args, err := ioutil.ReadFile("args")
+	assert.Nil(t, err)
+	assert.Equal(t, resources[0]+" --flag arg", string(args))
+}
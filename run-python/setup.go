@@ -10,10 +10,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	jww "github.com/spf13/jwalterweatherman"
 	"github.com/stoic-cli/stoic-cli-core"
+	"github.com/stoic-cli/stoic-cli-core/util"
 )
 
 const (
@@ -22,6 +24,7 @@ const (
 
 	readyBase        = ".ready"
 	requirementsBase = ".requirements"
+	versionsBase     = "versions"
 
 	pipRequirements = "" +
 		"pip==10.0.1 --hash=sha256:717cdffb2833be8409433a93746744b59505f42146e8d37de6c62b430e25d6d7\n" +
@@ -70,25 +73,79 @@ func getPipScript(cache stoic.Cache) (string, error) {
 	return script.Name(), nil
 }
 
-func setupPythonEnv(root string, python string, cache stoic.Cache) (PythonEnv, error) {
+// pythonVersion returns the version of the python interpreter, in the form
+// major.minor.micro.
+func pythonVersion(python string) (string, error) {
+	cmd := exec.Command(python, "-S", "-c",
+		"import sys; print('.'.join(map(str, sys.version_info[:3])))")
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err,
+			"unable to determine version of python executable %v", python)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// cachedPythonVersion returns the version of the python interpreter, which is
+// only detected again when the interpreter changes.
+func cachedPythonVersion(root string, python string) (string, error) {
+	return util.CachedVersion(filepath.Join(root, versionsBase), python, pythonVersion)
+}
+
+func pythonEnvRequirements(python string, version string, toolchain []string) []byte {
 	buf := bytes.NewBuffer(nil)
-	fmt.Fprintf(buf, "# Python: %s\n%s", python, pipRequirements)
+	fmt.Fprintf(buf, "# Python: %s\n# Version: %s\n", python, version)
+	if len(toolchain) != 0 {
+		fmt.Fprintf(buf, "%s\n", strings.Join(toolchain, "\n"))
+	} else if strings.HasPrefix(version, "2.") {
+		buf.WriteString(pipRequirements)
+	}
+	return buf.Bytes()
+}
 
-	pipCache := filepath.Join(root, "pip-cache")
+// pythonEnvRoot returns the base path of the python environment for python.
+func pythonEnvRoot(root string, python string, version string, toolchain []string) string {
 	pythonName := filepath.Base(python)
+	envHash := sha256.Sum256(pythonEnvRequirements(python, version, toolchain))
+	return filepath.Join(root, fmt.Sprintf("%s-%.4x", pythonName, envHash))
+}
+
+// virtualEnvRoot returns the base path of the virtual environment for the
+// given requirements, within the python environment at envRoot.
+func virtualEnvRoot(envRoot string, requirements []byte) string {
+	venvHash := fmt.Sprintf("%x", sha256.Sum256(requirements))
+	return filepath.Join(envRoot, "env", venvHash[:2], venvHash[2:])
+}
+
+func setupPythonEnv(root string, python string, toolchain []string, cache stoic.Cache) (PythonEnv, error) {
+	pipCache := filepath.Join(root, "pip-cache")
+
+	version, err := cachedPythonVersion(root, python)
+	if err != nil {
+		return nil, err
+	}
 
-	requirements := buf.Bytes()
-	envHash := sha256.Sum256(requirements)
-	envRoot := filepath.Join(root, fmt.Sprintf("%s-%.4x", pythonName, envHash))
+	requirements := pythonEnvRequirements(python, version, toolchain)
+	envRoot := pythonEnvRoot(root, python, version, toolchain)
 
-	pe := newPythonEnv(envRoot, python, pipCache)
+	pe := newPythonEnv(envRoot, python, pipCache, version, toolchain)
+
+	// Environments are shared by tools across stoic processes
+	envLock, err := util.LockSharedResource(envRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer envLock.Unlock()
 
 	marker := filepath.Join(envRoot, readyBase)
 	if fileExists(marker) {
+		util.TouchSharedResource(envRoot)
 		return pe, nil
 	}
 
-	err := os.MkdirAll(pe.Scripts(), os.ModePerm)
+	err = os.MkdirAll(pe.Scripts(), os.ModePerm)
 	if err != nil {
 		return nil, errors.Wrap(err,
 			"unable to setup directory for python environmnent")
@@ -107,20 +164,42 @@ func setupPythonEnv(root string, python string, cache stoic.Cache) (PythonEnv, e
 			"unable to write requirements for python environment")
 	}
 
+	// Virtual environments are set up with venv and ensurepip, which don't
+	// need a setup of their own, nor network access.
+	if !pe.UsesVenv() {
+		err = setupPip(pe, envRequirements, cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ioutil.WriteFile(marker, currentTimestamp(), 0644); err != nil {
+		jww.DEBUG.Printf("failed to mark python environment as ready: %v", err)
+	}
+	return pe, nil
+}
+
+// setupPip installs pip, setuptools, virtualenv, and wheel in the python
+// environment using get-pip.py.
+func setupPip(pe PythonEnv, envRequirements string, cache stoic.Cache) error {
 	getPip, err := getPipScript(cache)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer os.Remove(getPip)
 
-	cmd := exec.Command(python, getPip,
+	args := []string{getPip,
 		"--disable-pip-version-check",
 		"--no-warn-script-location",
 		"--ignore-installed",
 		"--isolated",
-		"--cache-dir", pipCache,
+		"--cache-dir", pe.PipCache(),
 		pe.(*pythonEnv).InstallModeForSetup(),
-		"--require-hashes",
+	}
+	if len(pe.Toolchain()) == 0 {
+		args = append(args, "--require-hashes")
+	}
+	args = append(args,
 		"--requirement", envRequirements,
 
 		// Disable implicit packages
@@ -130,6 +209,8 @@ func setupPythonEnv(root string, python string, cache stoic.Cache) (PythonEnv, e
 		// MUST BE LAST parameter. See https://github.com/pypa/pip/issues/3685
 		"--src")
 
+	cmd := exec.Command(pe.Python(), args...)
+
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 
@@ -137,29 +218,29 @@ func setupPythonEnv(root string, python string, cache stoic.Cache) (PythonEnv, e
 
 	err = cmd.Run()
 	if err != nil {
-		return nil, errors.Wrap(err,
-			"unable to setup pip in python environment")
-	}
-
-	if err := ioutil.WriteFile(marker, currentTimestamp(), 0644); err != nil {
-		jww.DEBUG.Printf("failed to mark python environment as ready: %v", err)
+		return errors.Wrap(err, "unable to setup pip in python environment")
 	}
-	return pe, nil
+	return nil
 }
 
-func setupVirtualEnv(pe PythonEnv, requirementsFile string) (VirtualEnv, error) {
-	requirements, err := ioutil.ReadFile(requirementsFile)
+// setupVirtualEnv sets up a virtual environment with the requirements locked
+// in lockfile, which may be empty.
+func setupVirtualEnv(pe PythonEnv, lockfile string) (VirtualEnv, error) {
+	lock, err := readLockfile(lockfile)
 	if err != nil {
-		return nil, errors.Wrapf(err,
-			"unable to read requirements for virtual environment from %v",
-			requirementsFile)
+		return nil, err
 	}
 
-	venvHash := fmt.Sprintf("%x", sha256.Sum256(requirements))
-	venvBase := filepath.Join(pe.Root(), "env", venvHash[:2], venvHash[2:])
+	venvBase := virtualEnvRoot(pe.Root(), lock)
 
 	ve := newVirtualEnv(pe, venvBase)
 
+	venvLock, err := util.LockSharedResource(venvBase)
+	if err != nil {
+		return nil, err
+	}
+	defer venvLock.Unlock()
+
 	marker := filepath.Join(ve.Root(), readyBase)
 	if fileExists(marker) {
 		// macOS: homebrew installations of python can be regularly updated,
@@ -167,20 +248,29 @@ func setupVirtualEnv(pe PythonEnv, requirementsFile string) (VirtualEnv, error)
 		python, err := os.Readlink(filepath.Join(ve.Root(), ".Python"))
 		if os.IsNotExist(err) {
 			// Breakage detection does not apply, assume ve is good
+			util.TouchSharedResource(venvBase)
 			return ve, nil
 		}
 		if fileExists(python) {
+			util.TouchSharedResource(venvBase)
 			return ve, nil
 		}
 	}
 
-	initVenv := exec.Command(pe.Python(),
-		"-S", "-m", "virtualenv", "--quiet",
+	var initVenv *exec.Cmd
+	if pe.UsesVenv() {
+		// pip is installed from the wheel bundled with ensurepip
+		initVenv = exec.Command(pe.Python(),
+			"-m", "venv", "--clear", ve.Root())
+	} else {
+		initVenv = exec.Command(pe.Python(),
+			"-S", "-m", "virtualenv", "--quiet",
 
-		// Disable implicit packages
-		"--no-pip", "--no-setuptools", "--no-wheel",
-		ve.Root(),
-	)
+			// Disable implicit packages
+			"--no-pip", "--no-setuptools", "--no-wheel",
+			ve.Root(),
+		)
+	}
 	initVenv.Stdout = os.Stderr
 	initVenv.Stderr = os.Stderr
 	initVenv.Env = pe.Environ()
@@ -199,6 +289,11 @@ func setupVirtualEnv(pe PythonEnv, requirementsFile string) (VirtualEnv, error)
 		}
 	}()
 
+	requirements, err := exportRequirements(lockfile, lock)
+	if err != nil {
+		return nil, err
+	}
+
 	venvRequirements := filepath.Join(ve.Root(), requirementsBase)
 	err = ioutil.WriteFile(venvRequirements, requirements, 0644)
 	if err != nil {
@@ -206,6 +301,24 @@ func setupVirtualEnv(pe PythonEnv, requirementsFile string) (VirtualEnv, error)
 			"unable to write requirements in virtual environment")
 	}
 
+	if pe.UsesVenv() && len(pe.Toolchain()) != 0 {
+		installToolchain := exec.Command(ve.Python(), append([]string{
+			"-m", "pip", "install",
+			"--disable-pip-version-check",
+			"--no-warn-script-location",
+			"--upgrade",
+		}, pe.Toolchain()...)...)
+		installToolchain.Stdout = os.Stderr
+		installToolchain.Stderr = os.Stderr
+		installToolchain.Env = pe.Environ()
+
+		err = installToolchain.Run()
+		if err != nil {
+			return nil, errors.Wrap(err,
+				"unable to setup pip toolchain in virtual environment")
+		}
+	}
+
 	installRequirements := exec.Command(ve.Python(),
 		"-m", "pip", "install",
 		"--disable-pip-version-check",
@@ -228,3 +341,47 @@ func setupVirtualEnv(pe PythonEnv, requirementsFile string) (VirtualEnv, error)
 	}
 	return ve, nil
 }
+
+// setupPackage installs the checkout at checkoutDir as a package, for use with
+// the virtual environment. Dependencies are expected to be installed in the
+// virtual environment if withDeps is false.
+func setupPackage(ve VirtualEnv, checkoutDir string, withDeps bool) (string, error) {
+	target := packageTarget(checkoutDir)
+
+	marker := filepath.Join(target, readyBase)
+	if fileExists(marker) {
+		return target, nil
+	}
+
+	err := os.RemoveAll(target)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to clean up package installation")
+	}
+
+	args := []string{
+		"-m", "pip", "install",
+		"--disable-pip-version-check",
+		"--no-warn-script-location",
+		"--target", target,
+	}
+	if !withDeps {
+		args = append(args, "--no-deps")
+	}
+	args = append(args, checkoutDir)
+
+	installPackage := exec.Command(ve.Python(), args...)
+	installPackage.Stdout = os.Stderr
+	installPackage.Stderr = os.Stderr
+	installPackage.Env = ve.Environ()
+
+	err = installPackage.Run()
+	if err != nil {
+		_ = os.RemoveAll(target)
+		return "", errors.Wrapf(err, "unable to install package from %v", checkoutDir)
+	}
+
+	if err := ioutil.WriteFile(marker, currentTimestamp(), 0644); err != nil {
+		jww.DEBUG.Printf("failed to mark package installation as ready: %v", err)
+	}
+	return target, nil
+}
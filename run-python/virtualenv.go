@@ -49,8 +49,11 @@ func (ve *virtualEnv) EnvPath() string {
 
 func (ve *virtualEnv) Environ() []string {
 	// TODO: Should filter out PYTHONHOME from environment, if set
-	return append(os.Environ(),
+	environ := append(os.Environ(),
 		"PATH="+ve.EnvPath(),
-		"PYTHONPATH="+ve.pe.SitePackages(),
 		"VIRTUAL_ENV="+ve.root)
+	if !ve.pe.UsesVenv() {
+		environ = append(environ, "PYTHONPATH="+ve.pe.SitePackages())
+	}
+	return environ
 }
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/pkg/errors"
+	"github.com/stoic-cli/stoic-cli-core/util"
+)
+
+var errExecUnsupported = errors.New("exec is not supported on this platform")
+
+// runChild runs cmd as a child process, relaying signals received by stoic to
+// it until it exits. The error returned is a *util.ExitStatusError if the tool
+// failed, which holds its exact exit status.
+func runChild(cmd *exec.Cmd) error {
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals)
+	defer signal.Stop(signals)
+
+	// Stop along with the tool, so that job control works as expected
+	if len(jobControlSignals) != 0 {
+		signal.Reset(jobControlSignals...)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if isForwardedSignal(sig) {
+					cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &util.ExitStatusError{ExitError: exitErr}
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stoic-cli/stoic-cli-core/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunChild(t *testing.T) {
+	data := []struct {
+		Name     string
+		Script   string
+		Signal   os.Signal
+		ExitCode int
+	}{
+		{"Success", "exit 0", nil, 0},
+		{"ExitCode", "exit 42", nil, 42},
+		{"Forwarded", `trap "exit 3" USR1; touch ready; while :; do sleep 0.01; done`, syscall.SIGUSR1, 3},
+	}
+
+	for _, d := range data {
+		t.Run(d.Name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "run-child")
+			assert.Nil(t, err)
+			defer os.RemoveAll(dir)
+
+			cmd := exec.Command("/bin/sh", "-c", d.Script)
+			cmd.Dir = dir
+
+			result := make(chan error)
+			go func() { result <- runChild(cmd) }()
+
+			if d.Signal != nil {
+				for i := 0; i < 500; i++ {
+					if _, err := os.Stat(filepath.Join(dir, "ready")); err == nil {
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				assert.Nil(t, syscall.Kill(os.Getpid(), d.Signal.(syscall.Signal)))
+			}
+
+			err = <-result
+			if d.ExitCode == 0 {
+				assert.Nil(t, err)
+				return
+			}
+
+			exitErr, ok := err.(*util.ExitStatusError)
+			if assert.True(t, ok, "%v", err) {
+				assert.Equal(t, d.ExitCode, exitErr.ExitCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// jobControlSignals stop and continue stoic along with the tool, as they are
+// sent to the whole foreground process group.
+var jobControlSignals = []os.Signal{syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU}
+
+// execCommand replaces the stoic process with cmd. It only returns if the
+// command can't be executed.
+func execCommand(cmd *exec.Cmd) error {
+	environment := cmd.Env
+	if environment == nil {
+		environment = os.Environ()
+	}
+	return syscall.Exec(cmd.Path, cmd.Args, environment)
+}
+
+// isForwardedSignal is true for signals that are relayed to the tool when it
+// runs as a child process. SIGCHLD is about the tool itself, and SIGURG is
+// used internally by the Go runtime.
+func isForwardedSignal(sig os.Signal) bool {
+	return sig != syscall.SIGCHLD && sig != syscall.SIGURG
+}
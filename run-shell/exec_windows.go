@@ -0,0 +1,23 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+// jobControlSignals stop and continue stoic along with the tool. There are
+// none on Windows.
+var jobControlSignals []os.Signal
+
+// execCommand replaces the stoic process with cmd, which Windows doesn't
+// support. Tools always run as a child process.
+func execCommand(cmd *exec.Cmd) error {
+	return errExecUnsupported
+}
+
+// isForwardedSignal is true for signals that are relayed to the tool when it
+// runs as a child process. Console control events already reach all processes
+// attached to the console, and can't be sent to a process otherwise.
+func isForwardedSignal(sig os.Signal) bool {
+	return false
+}
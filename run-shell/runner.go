@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/shlex"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"github.com/stoic-cli/stoic-cli-core"
 	"github.com/stoic-cli/stoic-cli-core/tool"
 )
@@ -45,6 +46,11 @@ type Options struct {
 	Command     string
 	Environment map[string]string
 	Parameters  map[string]interface{}
+
+	// ChildProcess keeps stoic running as the parent of the tool, relaying
+	// signals to it and exiting with its exit status. By default, stoic is
+	// replaced by the tool on platforms that support it.
+	ChildProcess bool `mapstructure:"child-process"`
 }
 
 type Runner struct {
@@ -151,9 +157,17 @@ func (sr Runner) Run(checkout tool.Checkout, name string, args []string) error {
 		return nil
 	}
 
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	cmd.Args = append(cmd.Args, args...)
-	return cmd.Run()
+
+	if !sr.Options.ChildProcess {
+		err = execCommand(cmd)
+		if err != errExecUnsupported {
+			return errors.Wrapf(err, "unable to run %v", cmd.Path)
+		}
+	}
+	return runChild(cmd)
 }
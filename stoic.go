@@ -26,22 +26,47 @@ type Tool interface {
 	UpdateFrequency() tool.UpdateFrequency
 	UpstreamVersion() tool.Version
 	LastUpdate() time.Time
+	IsUpdateDue() bool
 
 	CurrentVersion() tool.Version
+	HeldVersion() tool.Version
+	SatisfiesVersionConstraint() bool
 
 	CurrentCheckout() tool.Checkout
 	CheckoutForVersion(tool.Version) tool.Checkout
 }
 
+// CheckoutRecord describes a checkout of a tool, as recorded in its state.
+type CheckoutRecord struct {
+	tool.Checkout
+
+	Created time.Time
+
+	// LastSetCurrent is the last time the checkout was marked as current, or
+	// the zero time if it never was.
+	LastSetCurrent time.Time
+
+	IsCurrent bool
+}
+
 type Stoic interface {
 	Root() string
 	ConfigFile() string
 
 	Parameters() map[string]interface{}
 
+	// HostConfig returns settings for accessing a host, as configured by the
+	// user.
+	HostConfig(host string) format.HostConfig
+
 	Cache() Cache
 
 	Tools() []Tool
 
 	RunTool(name string, args []string) error
+	UpdateTool(name string, dryRun bool) (from, to tool.Version, err error)
+	RollbackTool(name string, hold bool) (from, to tool.Version, err error)
+	ToolHistory(name string) ([]CheckoutRecord, error)
+	Prepare(names []string, concurrency int) error
+	CollectGarbage(keep int) (int64, error)
 }
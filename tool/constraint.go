@@ -0,0 +1,256 @@
+package tool
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const NoConstraint = VersionConstraint("")
+
+// VersionConstraint restricts the versions of a tool to a range of semantic
+// versions (e.g., "^1.4" or ">=2.0, <3").
+//
+// A constraint is made of comma-separated comparisons, all of which must be
+// satisfied, optionally combined with "||" into alternatives. Comparisons use
+// the =, !=, <, <=, > and >= operators, as well as:
+//
+//	^1.4      compatible versions: >=1.4.0, <2.0.0 (or <0.5.0 for ^0.4)
+//	~1.4.2    patch releases: >=1.4.2, <1.5.0 (~1 allows >=1.0.0, <2.0.0)
+//	1.4, 1.x  any version with the given prefix: >=1.4.0, <1.5.0
+//	*         any version
+//
+// Pre-release versions only satisfy a constraint that explicitly includes a
+// pre-release of the same major, minor and patch version (e.g., >=2.0.0-rc.1).
+// Versions that aren't semantic versions, such as commit hashes, never do.
+type VersionConstraint string
+
+var constraintTermRegexp = regexp.MustCompile(
+	`^(=|!=|<=|>=|<|>|\^|~)?\s*([^\s]+)$`)
+
+type comparisonOp int
+
+const (
+	opEqual comparisonOp = iota
+	opNotEqual
+	opLess
+	opLessOrEqual
+	opGreater
+	opGreaterOrEqual
+)
+
+type comparison struct {
+	op      comparisonOp
+	version Semver
+}
+
+func (c comparison) allows(sv Semver) bool {
+	result := sv.Compare(c.version)
+	switch c.op {
+	case opEqual:
+		return result == 0
+	case opNotEqual:
+		return result != 0
+	case opLess:
+		return result < 0
+	case opLessOrEqual:
+		return result <= 0
+	case opGreater:
+		return result > 0
+	default:
+		return result >= 0
+	}
+}
+
+// comparisons must all be satisfied.
+type comparisons []comparison
+
+func (cs comparisons) allows(sv Semver) bool {
+	for _, c := range cs {
+		if !c.allows(sv) {
+			return false
+		}
+	}
+
+	if !sv.IsPrerelease() {
+		return true
+	}
+	for _, c := range cs {
+		if c.version.IsPrerelease() && c.version.Major == sv.Major &&
+			c.version.Minor == sv.Minor && c.version.Patch == sv.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSet is true unless vc is empty.
+func (vc VersionConstraint) IsSet() bool {
+	return strings.TrimSpace(string(vc)) != ""
+}
+
+// Validate returns an error if vc is not a valid constraint.
+func (vc VersionConstraint) Validate() error {
+	if !vc.IsSet() {
+		return nil
+	}
+	_, err := vc.parse()
+	return err
+}
+
+// Allows is true if v satisfies the constraint. Any version satisfies an
+// empty constraint, while none satisfies an invalid one.
+func (vc VersionConstraint) Allows(v Version) bool {
+	if !vc.IsSet() {
+		return true
+	}
+
+	alternatives, err := vc.parse()
+	if err != nil {
+		return false
+	}
+
+	sv, ok := v.Semver()
+	if !ok {
+		return false
+	}
+
+	for _, cs := range alternatives {
+		if cs.allows(sv) {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the highest of versions that satisfies the constraint, or
+// NullVersion if none does. Without a constraint, this is the highest
+// semantic version that is not a pre-release.
+func (vc VersionConstraint) Highest(versions []Version) Version {
+	if !vc.IsSet() {
+		return HighestVersion(versions, false)
+	}
+
+	var allowed []Version
+	for _, v := range versions {
+		if vc.Allows(v) {
+			allowed = append(allowed, v)
+		}
+	}
+	return HighestVersion(allowed, true)
+}
+
+func (vc VersionConstraint) parse() ([]comparisons, error) {
+	var alternatives []comparisons
+	for _, alternative := range strings.Split(string(vc), "||") {
+		var cs comparisons
+		for _, term := range strings.Split(alternative, ",") {
+			termComparisons, err := parseConstraintTerm(strings.TrimSpace(term))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid version constraint, '%v'", vc)
+			}
+			cs = append(cs, termComparisons...)
+		}
+		alternatives = append(alternatives, cs)
+	}
+	return alternatives, nil
+}
+
+func parseConstraintTerm(term string) (comparisons, error) {
+	match := constraintTermRegexp.FindStringSubmatch(term)
+	if match == nil {
+		return nil, errors.Errorf("unable to parse '%v'", term)
+	}
+	operator, value := match[1], match[2]
+
+	// Wildcards stand for unspecified version numbers
+	for _, wildcard := range []string{".*", ".x", ".X"} {
+		for strings.HasSuffix(value, wildcard) {
+			value = strings.TrimSuffix(value, wildcard)
+		}
+	}
+
+	var version Semver
+	var specified int
+	if value == "*" || value == "x" || value == "X" {
+		if operator != "" && operator != "=" {
+			return nil, errors.Errorf("'%v' requires a version", term)
+		}
+	} else {
+		var ok bool
+		version, specified, ok = parseSemver(value)
+		if !ok {
+			return nil, errors.Errorf("'%v' is not a semantic version", value)
+		}
+	}
+
+	exact := specified == 3
+	lower := comparison{opGreaterOrEqual, version}
+	upper := func(bump int) comparison {
+		return comparison{opLess, bumpSemver(version, bump)}
+	}
+
+	switch operator {
+	case "", "=":
+		switch {
+		case specified == 0:
+			return nil, nil
+		case exact:
+			return comparisons{{opEqual, version}}, nil
+		}
+		return comparisons{lower, upper(specified)}, nil
+
+	case "!=":
+		if !exact {
+			return nil, errors.Errorf("'%v' requires a full version", term)
+		}
+		return comparisons{{opNotEqual, version}}, nil
+
+	case "<":
+		return comparisons{{opLess, version}}, nil
+
+	case ">=":
+		return comparisons{lower}, nil
+
+	case "<=":
+		if exact {
+			return comparisons{{opLessOrEqual, version}}, nil
+		}
+		return comparisons{upper(specified)}, nil
+
+	case ">":
+		if exact {
+			return comparisons{{opGreater, version}}, nil
+		}
+		return comparisons{{opGreaterOrEqual, bumpSemver(version, specified)}}, nil
+
+	case "~":
+		if specified == 1 {
+			return comparisons{lower, upper(1)}, nil
+		}
+		return comparisons{lower, upper(2)}, nil
+
+	default: // "^"
+		switch {
+		case version.Major != 0 || specified == 1:
+			return comparisons{lower, upper(1)}, nil
+		case version.Minor != 0 || specified == 2:
+			return comparisons{lower, upper(2)}, nil
+		}
+		return comparisons{lower, upper(3)}, nil
+	}
+}
+
+// bumpSemver returns the lowest version that doesn't share the first
+// numbers of sv, out of major, minor and patch numbers.
+func bumpSemver(sv Semver, numbers int) Semver {
+	switch numbers {
+	case 1:
+		return Semver{Major: sv.Major + 1}
+	case 2:
+		return Semver{Major: sv.Major, Minor: sv.Minor + 1}
+	default:
+		return Semver{Major: sv.Major, Minor: sv.Minor, Patch: sv.Patch + 1}
+	}
+}
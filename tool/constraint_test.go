@@ -0,0 +1,131 @@
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	data := []struct {
+		Constraint VersionConstraint
+		Allowed    []Version
+		Rejected   []Version
+	}{
+		{
+			Constraint: NoConstraint,
+			Allowed:    []Version{"1.0.0", "v2.0.0-rc.1", "master"},
+		},
+		{
+			Constraint: "1.4.2",
+			Allowed:    []Version{"1.4.2", "v1.4.2", "1.4.2+build"},
+			Rejected:   []Version{"1.4.3", "1.4.2-rc.1"},
+		},
+		{
+			Constraint: "=1.4",
+			Allowed:    []Version{"1.4.0", "1.4.9"},
+			Rejected:   []Version{"1.3.9", "1.5.0"},
+		},
+		{
+			Constraint: "1.x",
+			Allowed:    []Version{"1.0.0", "1.99.0"},
+			Rejected:   []Version{"0.9.0", "2.0.0"},
+		},
+		{
+			Constraint: "*",
+			Allowed:    []Version{"0.0.1", "v10.0.0"},
+			Rejected:   []Version{"1.0.0-rc.1", "master"},
+		},
+		{
+			Constraint: "^1.4",
+			Allowed:    []Version{"1.4.0", "v1.4.1", "1.10.0"},
+			Rejected:   []Version{"1.3.9", "2.0.0", "2.0.0-rc.1", "1.5.0-rc.1"},
+		},
+		{
+			Constraint: "^0.4.1",
+			Allowed:    []Version{"0.4.1", "0.4.9"},
+			Rejected:   []Version{"0.4.0", "0.5.0"},
+		},
+		{
+			Constraint: "^0.0.3",
+			Allowed:    []Version{"0.0.3"},
+			Rejected:   []Version{"0.0.4"},
+		},
+		{
+			Constraint: "~1.4.2",
+			Allowed:    []Version{"1.4.2", "1.4.10"},
+			Rejected:   []Version{"1.4.1", "1.5.0"},
+		},
+		{
+			Constraint: "~1",
+			Allowed:    []Version{"1.0.0", "1.9.0"},
+			Rejected:   []Version{"2.0.0"},
+		},
+		{
+			Constraint: ">=2.0, <3",
+			Allowed:    []Version{"2.0.0", "v2.9.9"},
+			Rejected:   []Version{"1.9.9", "3.0.0", "3.0.0-rc.1", "2.1.0-beta"},
+		},
+		{
+			Constraint: ">1.4, <=2.1",
+			Allowed:    []Version{"1.5.0", "2.1.9"},
+			Rejected:   []Version{"1.4.9", "2.2.0"},
+		},
+		{
+			Constraint: ">1.4.2, !=1.4.5",
+			Allowed:    []Version{"1.4.3", "1.4.6"},
+			Rejected:   []Version{"1.4.2", "1.4.5"},
+		},
+		{
+			Constraint: ">=2.0.0-rc.1",
+			Allowed:    []Version{"2.0.0-rc.1", "2.0.0-rc.2", "2.0.0", "2.1.0"},
+			Rejected:   []Version{"2.0.0-beta", "2.1.0-rc.1", "1.9.0"},
+		},
+		{
+			Constraint: "^1 || ^3",
+			Allowed:    []Version{"1.2.0", "3.0.0"},
+			Rejected:   []Version{"2.0.0"},
+		},
+		{
+			Constraint: "^1.0",
+			Rejected:   []Version{"0123456789abcdef0123456789abcdef01234567", ""},
+		},
+	}
+
+	for _, test := range data {
+		t.Run(string(test.Constraint), func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Nil(test.Constraint.Validate())
+			for _, v := range test.Allowed {
+				assert.True(test.Constraint.Allows(v), "version: %v", v)
+			}
+			for _, v := range test.Rejected {
+				assert.False(test.Constraint.Allows(v), "version: %v", v)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []VersionConstraint{
+		"latest", ">=1.0,", "^", ">=1.0 <2.0", "!=1.4", ">*", "1.2.3.4", "=> 1.0",
+	} {
+		t.Run(string(constraint), func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.NotNil(constraint.Validate())
+			assert.False(constraint.Allows("1.0.0"))
+		})
+	}
+}
+
+func TestVersionConstraintHighest(t *testing.T) {
+	assert := assert.New(t)
+
+	versions := []Version{"v1.3.0", "v1.10.2", "v1.9.0", "v2.0.0", "v2.1.0-rc.1", "nightly"}
+	assert.Equal(Version("v1.10.2"), VersionConstraint("^1.4").Highest(versions))
+	assert.Equal(Version("v2.0.0"), NoConstraint.Highest(versions))
+	assert.Equal(Version("v2.1.0-rc.1"), VersionConstraint(">=2.1.0-rc.1").Highest(versions))
+	assert.Equal(NullVersion, VersionConstraint("^3").Highest(versions))
+}
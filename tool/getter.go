@@ -1,8 +1,28 @@
 package tool
 
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrVersionNotFetched is returned by Getter.CheckoutTo when the artifacts for
+// the requested version are no longer available locally (e.g., because they
+// were evicted from the cache). Fetching the version again should allow the
+// checkout to proceed.
+var ErrVersionNotFetched = errors.New("version is not available locally")
+
 type Getter interface {
 	FetchLatest() (Version, error)
 	FetchVersion(version Version) error
 
 	CheckoutTo(version Version, path string) error
 }
+
+// LatestVersionGetter is implemented by getters that can look up the latest
+// upstream version without fetching it (e.g., without downloading release
+// assets).
+type LatestVersionGetter interface {
+	Getter
+
+	// LatestVersion returns the latest upstream version.
+	LatestVersion() (Version, error)
+}
@@ -4,3 +4,18 @@ type Runner interface {
 	Setup(checkout Checkout) error
 	Run(checkout Checkout, name string, args []string) error
 }
+
+// SharedResourcesRunner is implemented by runners that set up resources
+// outside of checkouts, which may be shared between checkouts (e.g., virtual
+// environments). It allows resources no longer used by any checkout to be
+// removed.
+type SharedResourcesRunner interface {
+	Runner
+
+	// SharedResources returns the paths of all resources managed by the
+	// runner that are currently set up.
+	SharedResources() ([]string, error)
+
+	// SharedResourcesFor returns the paths of resources used by checkout.
+	SharedResourcesFor(checkout Checkout) ([]string, error)
+}
@@ -0,0 +1,186 @@
+package tool
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var semverRegexp = regexp.MustCompile(`^[vV]?` +
+	`(0|[1-9][0-9]*)(?:\.(0|[1-9][0-9]*)(?:\.(0|[1-9][0-9]*))?)?` +
+	`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?` +
+	`(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// Semver is a semantic version, see https://semver.org.
+type Semver struct {
+	Major, Minor, Patch uint64
+
+	// Prerelease holds the dot-separated identifiers of a pre-release
+	// version, if any (e.g., [rc 1] for 1.0.0-rc.1).
+	Prerelease []string
+
+	// Build holds build metadata, which is ignored in comparisons.
+	Build string
+}
+
+// Semver parses v as a semantic version. A leading "v" is tolerated, as are
+// missing minor and patch numbers, which default to 0 (e.g., v1.4 is parsed
+// as 1.4.0). It returns false if v is not a semantic version, such as a
+// commit hash.
+func (v Version) Semver() (Semver, bool) {
+	sv, _, ok := parseSemver(string(v))
+	return sv, ok
+}
+
+// parseSemver parses a semantic version, also returning the number of
+// version numbers that were specified.
+func parseSemver(value string) (Semver, int, bool) {
+	match := semverRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return Semver{}, 0, false
+	}
+
+	var sv Semver
+	var specified int
+	for i, number := range []*uint64{&sv.Major, &sv.Minor, &sv.Patch} {
+		if match[i+1] == "" {
+			break
+		}
+
+		n, err := strconv.ParseUint(match[i+1], 10, 64)
+		if err != nil {
+			return Semver{}, 0, false
+		}
+		*number = n
+		specified++
+	}
+
+	if match[4] != "" {
+		sv.Prerelease = strings.Split(match[4], ".")
+	}
+	sv.Build = match[5]
+
+	return sv, specified, true
+}
+
+// IsPrerelease is true for pre-release versions (e.g., 1.0.0-rc.1).
+func (sv Semver) IsPrerelease() bool {
+	return len(sv.Prerelease) != 0
+}
+
+// Compare returns -1, 0 or 1 if sv precedes, is equivalent to, or follows
+// other, according to semantic versioning precedence rules.
+func (sv Semver) Compare(other Semver) int {
+	if c := compareUint(sv.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(sv.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(sv.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	// Pre-release versions precede the associated normal version
+	switch {
+	case !sv.IsPrerelease() && !other.IsPrerelease():
+		return 0
+	case !sv.IsPrerelease():
+		return 1
+	case !other.IsPrerelease():
+		return -1
+	}
+
+	for i := 0; i < len(sv.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := comparePrereleaseIdentifiers(sv.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(sv.Prerelease)), uint64(len(other.Prerelease)))
+}
+
+func (sv Semver) String() string {
+	s := strconv.FormatUint(sv.Major, 10) + "." +
+		strconv.FormatUint(sv.Minor, 10) + "." +
+		strconv.FormatUint(sv.Patch, 10)
+	if sv.IsPrerelease() {
+		s += "-" + strings.Join(sv.Prerelease, ".")
+	}
+	if sv.Build != "" {
+		s += "+" + sv.Build
+	}
+	return s
+}
+
+func comparePrereleaseIdentifiers(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+
+	// Numeric identifiers have lower precedence than alphanumeric ones
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compare returns -1, 0 or 1 if v precedes, is equivalent to, or follows
+// other. Semantic versions are compared according to semantic versioning
+// rules, ignoring any "v" prefix. Other versions precede semantic versions,
+// and are compared lexically.
+func (v Version) Compare(other Version) int {
+	sv, vIsSemver := v.Semver()
+	osv, otherIsSemver := other.Semver()
+
+	switch {
+	case vIsSemver && otherIsSemver:
+		return sv.Compare(osv)
+	case vIsSemver:
+		return 1
+	case otherIsSemver:
+		return -1
+	}
+	return strings.Compare(string(v), string(other))
+}
+
+// SortVersions sorts versions in ascending order, as defined by
+// Version.Compare.
+func SortVersions(versions []Version) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Compare(versions[j]) < 0
+	})
+}
+
+// HighestVersion returns the highest semantic version in versions, or
+// NullVersion if there is none. Pre-release versions are only considered if
+// includePrereleases is set.
+func HighestVersion(versions []Version, includePrereleases bool) Version {
+	highest := NullVersion
+	var highestSemver Semver
+
+	for _, v := range versions {
+		sv, ok := v.Semver()
+		if !ok || (sv.IsPrerelease() && !includePrereleases) {
+			continue
+		}
+		if highest == NullVersion || sv.Compare(highestSemver) > 0 {
+			highest, highestSemver = v, sv
+		}
+	}
+	return highest
+}
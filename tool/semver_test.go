@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionSemver(t *testing.T) {
+	data := []struct {
+		Version  Version
+		Expected string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"v1.2.3", "1.2.3"},
+		{"V1.2.3", "1.2.3"},
+		{"v1.4", "1.4.0"},
+		{"2", "2.0.0"},
+		{"1.0.0-rc.1", "1.0.0-rc.1"},
+		{"v1.0.0-rc.1+build.5", "1.0.0-rc.1+build.5"},
+		{"1.0.0+20180601", "1.0.0+20180601"},
+		{"", ""},
+		{"latest", ""},
+		{"v", ""},
+		{"01.2.3", ""},
+		{"1.2.3.4", ""},
+		{"1.2.3-", ""},
+		{"1.2.3-rc..1", ""},
+		{"0123456789abcdef0123456789abcdef01234567", ""},
+	}
+
+	for _, test := range data {
+		t.Run(string(test.Version), func(t *testing.T) {
+			assert := assert.New(t)
+
+			sv, ok := test.Version.Semver()
+			assert.Equal(test.Expected != "", ok)
+			if ok {
+				assert.Equal(test.Expected, sv.String())
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// In ascending order
+	ordered := []Version{
+		"0123456789abcdef0123456789abcdef01234567",
+		"master",
+		"0.9.0",
+		"v1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"v1.0.1",
+		"1.2",
+		"1.10.0",
+		"v2.0.0",
+	}
+
+	assert := assert.New(t)
+
+	for i := range ordered {
+		for j := range ordered {
+			expected := 0
+			switch {
+			case i < j:
+				expected = -1
+			case i > j:
+				expected = 1
+			}
+			assert.Equal(expected, ordered[i].Compare(ordered[j]),
+				"comparing %v and %v", ordered[i], ordered[j])
+		}
+	}
+
+	assert.Equal(0, Version("v1.2.0").Compare("1.2"))
+	assert.Equal(0, Version("1.2.0+linux").Compare("1.2.0+darwin"))
+
+	shuffled := []Version{"1.10.0", "master", "v2.0.0", "1.0.0", "1.0.0-rc.1", "1.2"}
+	SortVersions(shuffled)
+	assert.Equal([]Version{"master", "1.0.0-rc.1", "1.0.0", "1.2", "1.10.0", "v2.0.0"}, shuffled)
+}
+
+func TestHighestVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	versions := []Version{"v1.9.0", "v1.10.0", "v2.0.0-rc.1", "nightly"}
+	assert.Equal(Version("v1.10.0"), HighestVersion(versions, false))
+	assert.Equal(Version("v2.0.0-rc.1"), HighestVersion(versions, true))
+	assert.Equal(NullVersion, HighestVersion([]Version{"nightly"}, true))
+	assert.Equal(NullVersion, HighestVersion(nil, true))
+}
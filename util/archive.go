@@ -0,0 +1,436 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Archive formats supported by ExtractArchive.
+const (
+	ArchiveAuto   = "auto"
+	ArchiveNone   = "none"
+	ArchiveTar    = "tar"
+	ArchiveTarGz  = "tar.gz"
+	ArchiveTarXz  = "tar.xz"
+	ArchiveTarBz2 = "tar.bz2"
+	ArchiveZip    = "zip"
+	ArchiveGz     = "gz"
+)
+
+var archiveExtensions = []struct {
+	extension string
+	format    string
+}{
+	{".tar.gz", ArchiveTarGz},
+	{".tgz", ArchiveTarGz},
+	{".tar.xz", ArchiveTarXz},
+	{".txz", ArchiveTarXz},
+	{".tar.bz2", ArchiveTarBz2},
+	{".tbz2", ArchiveTarBz2},
+	{".tar", ArchiveTar},
+	{".zip", ArchiveZip},
+	{".gz", ArchiveGz},
+}
+
+// ExtractOptions controls how ExtractArchive unpacks an archive.
+type ExtractOptions struct {
+	// Format of the archive, one of the Archive* constants. The format is
+	// inferred from the name of the archive if empty or ArchiveAuto. Archives
+	// whose format can't be inferred, or whose Format is ArchiveNone, are
+	// copied as is.
+	Format string
+
+	// StripComponents removes the specified number of leading path elements
+	// from archive members. Members with fewer path elements are skipped.
+	StripComponents int
+}
+
+// ArchiveFormat infers the format of an archive from its name. It returns
+// ArchiveNone if the name has no known extension.
+func ArchiveFormat(name string) string {
+	name = strings.ToLower(name)
+	for _, ae := range archiveExtensions {
+		if strings.HasSuffix(name, ae.extension) {
+			return ae.format
+		}
+	}
+	return ArchiveNone
+}
+
+// ExtractArchive unpacks the archive named name, read from r, into dir.
+// Permission bits of archive members, including executable bits, are
+// preserved, subject to the umask. Members that would end up outside of dir,
+// including through links, are rejected.
+//
+// Plain gzip-compressed files are decompressed into dir, with the .gz
+// extension removed from name, and made executable.
+func ExtractArchive(r io.Reader, name, dir string, options ExtractOptions) error {
+	format := options.Format
+	if format == "" || format == ArchiveAuto {
+		format = ArchiveFormat(name)
+	}
+	if format == "tgz" {
+		format = ArchiveTarGz
+	}
+
+	switch format {
+	case ArchiveNone:
+		return writeFile(dir, filepath.Join(dir, name), r, 0666)
+
+	case ArchiveGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decompress %v", name)
+		}
+		defer gz.Close()
+
+		target := strings.TrimSuffix(name, path.Ext(name))
+		return writeFile(dir, filepath.Join(dir, target), gz, 0777)
+
+	case ArchiveTar:
+		return extractTar(r, dir, options.StripComponents)
+
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return errors.Wrapf(err, "unable to decompress %v", name)
+		}
+		defer gz.Close()
+		return extractTar(gz, dir, options.StripComponents)
+
+	case ArchiveTarBz2:
+		return extractTar(bzip2.NewReader(r), dir, options.StripComponents)
+
+	case ArchiveTarXz:
+		return extractTarXz(r, name, dir, options.StripComponents)
+
+	case ArchiveZip:
+		return extractZip(r, dir, options.StripComponents)
+
+	default:
+		return errors.Errorf("unsupported archive format, '%v'", format)
+	}
+}
+
+// extractTarXz relies on the xz command, as xz decompression isn't available
+// in the standard library.
+func extractTarXz(r io.Reader, name, dir string, stripComponents int) error {
+	xzPath, err := exec.LookPath("xz")
+	if err != nil {
+		return errors.Wrapf(err, "the xz command is required to extract %v", name)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(xzPath, "--decompress", "--stdout")
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	err = extractTar(stdout, dir, stripComponents)
+
+	// Drain output so xz doesn't block if extraction stopped early
+	io.Copy(ioutil.Discard, stdout)
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = errors.Wrapf(waitErr, "unable to decompress %v: %v",
+			name, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+func extractTar(r io.Reader, dir string, stripComponents int) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to read tar archive")
+		}
+
+		target, ok, err := archiveMemberPath(dir, header.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = makeDir(dir, target, mode)
+
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeFile(dir, target, tr, mode)
+
+		case tar.TypeSymlink:
+			err = makeSymlink(dir, target, header.Linkname)
+
+		case tar.TypeLink:
+			var source string
+			source, ok, err = archiveMemberPath(dir, header.Linkname, stripComponents)
+			if err == nil && !ok {
+				err = errors.Errorf("invalid hard link target in archive, '%v'",
+					header.Linkname)
+			}
+			if err == nil {
+				err = makeHardLink(dir, target, source)
+			}
+
+		default:
+			// Skip devices, FIFOs and the like
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(r io.Reader, dir string, stripComponents int) error {
+	// Reading zip archives requires random access
+	temp, err := ioutil.TempFile("", "stoic-zip-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	size, err := io.Copy(temp, r)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(temp, size)
+	if err != nil {
+		return errors.Wrap(err, "unable to read zip archive")
+	}
+
+	for _, f := range zr.File {
+		target, ok, err := archiveMemberPath(dir, f.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = makeDir(dir, target, mode.Perm())
+
+		case mode&os.ModeSymlink != 0:
+			var linkname []byte
+			linkname, err = readZipFile(f)
+			if err == nil {
+				err = makeSymlink(dir, target, string(linkname))
+			}
+
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			rc, err = f.Open()
+			if err == nil {
+				err = writeFile(dir, target, rc, mode.Perm())
+				rc.Close()
+			}
+
+		default:
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// archiveMemberPath maps the name of an archive member to a path in dir,
+// after removing stripComponents leading path elements. It returns false if
+// nothing is left of the name, and fails if the name would escape dir.
+func archiveMemberPath(dir, name string, stripComponents int) (string, bool, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	if path.IsAbs(name) {
+		return "", false, errors.Errorf("invalid absolute path in archive, '%v'", name)
+	}
+
+	var elems []string
+	for _, elem := range strings.Split(name, "/") {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			return "", false, errors.Errorf("invalid path in archive, '%v'", name)
+		}
+		elems = append(elems, elem)
+	}
+
+	if len(elems) <= stripComponents {
+		return "", false, nil
+	}
+	elems = elems[stripComponents:]
+
+	return filepath.Join(dir, filepath.Join(elems...)), true, nil
+}
+
+// checkWithin fails if target would be outside of dir, once symbolic links
+// in the existing part of its path are resolved.
+func checkWithin(dir, target string) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	existing := target
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	realExisting, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if !isWithin(realDir, realExisting) {
+		return errors.Errorf("invalid path in archive, '%v' is outside of %v", target, dir)
+	}
+	return nil
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." &&
+		!strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// makeSymlink creates a symbolic link at target, which must be in dir, to
+// linkname. Links to locations outside of dir are rejected.
+func makeSymlink(dir, target, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return errors.Errorf("invalid absolute symbolic link in archive, '%v'", linkname)
+	}
+
+	if err := checkWithin(dir, target); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	// The link is relative to where its parent really is, as the archive may
+	// have placed it behind other symbolic links
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if !isLinkWithin(realDir, realParent, linkname) {
+		return errors.Errorf("invalid symbolic link in archive, '%v' -> '%v'",
+			target, linkname)
+	}
+	return os.Symlink(linkname, target)
+}
+
+// isLinkWithin reports whether a symbolic link in parent to linkname points
+// within dir. References to parent directories must lead linkname, as they
+// would otherwise apply to wherever symbolic links in linkname point.
+func isLinkWithin(dir, parent, linkname string) bool {
+	named := false
+	for _, elem := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if named {
+				return false
+			}
+		default:
+			named = true
+		}
+	}
+	return isWithin(dir, filepath.Join(parent, linkname))
+}
+
+func makeHardLink(dir, target, source string) error {
+	if err := checkWithin(dir, source); err != nil {
+		return err
+	}
+	if err := checkWithin(dir, target); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Link(source, target)
+}
+
+func makeDir(dir, target string, mode os.FileMode) error {
+	if err := checkWithin(dir, target); err != nil {
+		return err
+	}
+	// Keep directories writable by the owner, so checkouts can be removed
+	return os.MkdirAll(target, mode|0700)
+}
+
+// writeFile creates a new file at target, which must be in dir, with the
+// content read from r. The permissions in mode are subject to the umask.
+func writeFile(dir, target string, r io.Reader, mode os.FileMode) error {
+	if err := checkWithin(dir, target); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
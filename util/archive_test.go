@@ -0,0 +1,266 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type archiveMember struct {
+	Name     string
+	Mode     os.FileMode
+	Content  string
+	Linkname string
+}
+
+func makeTar(t *testing.T, members []archiveMember) []byte {
+	var buffer bytes.Buffer
+	tw := tar.NewWriter(&buffer)
+
+	for _, m := range members {
+		header := &tar.Header{
+			Name:     m.Name,
+			Mode:     int64(m.Mode.Perm()),
+			Size:     int64(len(m.Content)),
+			Typeflag: tar.TypeReg,
+		}
+		switch {
+		case m.Mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case m.Mode&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = m.Linkname
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("unable to write tar header: %v", err)
+		}
+		if _, err := io.WriteString(tw, m.Content); err != nil {
+			t.Fatalf("unable to write tar member: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unable to write tar archive: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func makeGzip(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("unable to compress: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("unable to compress: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func makeZip(t *testing.T, members []archiveMember) []byte {
+	var buffer bytes.Buffer
+	zw := zip.NewWriter(&buffer)
+
+	for _, m := range members {
+		header := &zip.FileHeader{Name: m.Name}
+		header.SetMode(m.Mode)
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("unable to write zip header: %v", err)
+		}
+		if _, err := io.WriteString(w, m.Content+m.Linkname); err != nil {
+			t.Fatalf("unable to write zip member: %v", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unable to write zip archive: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+var testArchiveMembers = []archiveMember{
+	{Name: "tool-1.0/", Mode: os.ModeDir | 0755},
+	{Name: "tool-1.0/bin/", Mode: os.ModeDir | 0755},
+	{Name: "tool-1.0/bin/tool", Mode: 0755, Content: "#!/bin/sh\n"},
+	{Name: "tool-1.0/README", Mode: 0644, Content: "read me\n"},
+	{Name: "tool-1.0/bin/alias", Mode: os.ModeSymlink | 0777, Linkname: "tool"},
+}
+
+func assertExtracted(assert *assert.Assertions, dir string) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "bin", "tool"))
+	assert.Nil(err)
+	assert.Equal("#!/bin/sh\n", string(content))
+
+	fi, err := os.Stat(filepath.Join(dir, "bin", "tool"))
+	if assert.Nil(err) {
+		assert.Equal(os.FileMode(0700), fi.Mode().Perm()&0700)
+	}
+
+	fi, err = os.Stat(filepath.Join(dir, "README"))
+	if assert.Nil(err) {
+		assert.Equal(os.FileMode(0), fi.Mode().Perm()&0111)
+	}
+
+	linkname, err := os.Readlink(filepath.Join(dir, "bin", "alias"))
+	assert.Nil(err)
+	assert.Equal("tool", linkname)
+}
+
+func TestArchiveFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ArchiveTarGz, ArchiveFormat("tool-linux-amd64.tar.gz"))
+	assert.Equal(ArchiveTarGz, ArchiveFormat("tool-linux-amd64.tgz"))
+	assert.Equal(ArchiveTarXz, ArchiveFormat("tool-linux-amd64.tar.xz"))
+	assert.Equal(ArchiveTarBz2, ArchiveFormat("tool-linux-amd64.tar.bz2"))
+	assert.Equal(ArchiveTar, ArchiveFormat("tool-linux-amd64.tar"))
+	assert.Equal(ArchiveZip, ArchiveFormat("tool-windows-amd64.ZIP"))
+	assert.Equal(ArchiveGz, ArchiveFormat("tool-linux-amd64.gz"))
+	assert.Equal(ArchiveNone, ArchiveFormat("tool-linux-amd64"))
+	assert.Equal(ArchiveNone, ArchiveFormat("tool.exe"))
+}
+
+func TestExtractArchive(t *testing.T) {
+	tid := SetupTestInDir(t)
+	defer tid.Close()
+
+	options := ExtractOptions{StripComponents: 1}
+
+	t.Run("TarGz", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeGzip(t, makeTar(t, testArchiveMembers))
+		err := ExtractArchive(bytes.NewReader(archive), "tool.tar.gz", testDir, options)
+		assert.Nil(err)
+		assertExtracted(assert, testDir)
+	})
+	t.Run("TarXz", func(t *testing.T) {
+		if _, err := exec.LookPath("xz"); err != nil {
+			t.Skip("xz command is not available")
+		}
+
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		cmd := exec.Command("xz", "--compress", "--stdout")
+		cmd.Stdin = bytes.NewReader(makeTar(t, testArchiveMembers))
+		archive, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("unable to compress with xz: %v", err)
+		}
+
+		err = ExtractArchive(bytes.NewReader(archive), "tool.tar.xz", testDir, options)
+		assert.Nil(err)
+		assertExtracted(assert, testDir)
+	})
+	t.Run("Zip", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeZip(t, testArchiveMembers)
+		err := ExtractArchive(bytes.NewReader(archive), "tool.zip", testDir, options)
+		assert.Nil(err)
+		assertExtracted(assert, testDir)
+	})
+	t.Run("ExplicitFormat", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeZip(t, testArchiveMembers)
+		err := ExtractArchive(bytes.NewReader(archive), "download", testDir,
+			ExtractOptions{Format: ArchiveZip, StripComponents: 1})
+		assert.Nil(err)
+		assertExtracted(assert, testDir)
+	})
+	t.Run("WithoutStripComponents", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeTar(t, testArchiveMembers)
+		err := ExtractArchive(bytes.NewReader(archive), "tool.tar", testDir, ExtractOptions{})
+		assert.Nil(err)
+		assertExtracted(assert, filepath.Join(testDir, "tool-1.0"))
+	})
+	t.Run("Gz", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeGzip(t, []byte("binary"))
+		err := ExtractArchive(bytes.NewReader(archive), "tool-linux.gz", testDir, options)
+		assert.Nil(err)
+
+		fi, err := os.Stat(filepath.Join(testDir, "tool-linux"))
+		if assert.Nil(err) {
+			assert.Equal(os.FileMode(0700), fi.Mode().Perm()&0700)
+		}
+	})
+	t.Run("None", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		archive := makeGzip(t, []byte("binary"))
+		err := ExtractArchive(bytes.NewReader(archive), "tool.tar.gz", testDir,
+			ExtractOptions{Format: ArchiveNone})
+		assert.Nil(err)
+
+		content, err := ioutil.ReadFile(filepath.Join(testDir, "tool.tar.gz"))
+		assert.Nil(err)
+		assert.Equal(archive, content)
+	})
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		assert, testDir := tid.SetupTest(t)
+		assert.Nil(os.Mkdir(testDir, 0700))
+
+		err := ExtractArchive(bytes.NewReader(nil), "tool.rar", testDir,
+			ExtractOptions{Format: "rar"})
+		assert.NotNil(err)
+	})
+	t.Run("PathTraversal", func(t *testing.T) {
+		data := [][]archiveMember{
+			{{Name: "../evil", Mode: 0644, Content: "evil"}},
+			{{Name: "tool/../../evil", Mode: 0644, Content: "evil"}},
+			{{Name: "/tmp/evil", Mode: 0644, Content: "evil"}},
+			{{Name: "link", Mode: os.ModeSymlink | 0777, Linkname: "../evil"}},
+			{{Name: "link", Mode: os.ModeSymlink | 0777, Linkname: "/etc/passwd"}},
+			{
+				{Name: "here", Mode: os.ModeSymlink | 0777, Linkname: "."},
+				{Name: "up", Mode: os.ModeSymlink | 0777, Linkname: "here/.."},
+				{Name: "up/evil", Mode: 0644, Content: "evil"},
+			},
+			{
+				{Name: "here", Mode: os.ModeSymlink | 0777, Linkname: "."},
+				{Name: "here/evil", Mode: os.ModeSymlink | 0777, Linkname: "../evil"},
+			},
+			{
+				{Name: "here", Mode: os.ModeSymlink | 0777, Linkname: "."},
+				{Name: "evil", Mode: os.ModeSymlink | 0777, Linkname: "here/../evil"},
+			},
+		}
+
+		for _, members := range data {
+			assert, testDir := tid.SetupTest(t)
+			os.RemoveAll(testDir)
+			assert.Nil(os.Mkdir(testDir, 0700))
+
+			archive := makeTar(t, members)
+			err := ExtractArchive(bytes.NewReader(archive), "evil.tar", testDir, ExtractOptions{})
+			assert.NotNil(err, "archive member: %v", members[len(members)-1].Name)
+
+			_, err = os.Lstat("evil")
+			assert.True(os.IsNotExist(err))
+		}
+	})
+}
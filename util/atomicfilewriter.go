@@ -1,10 +1,12 @@
 package util
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -19,13 +21,28 @@ type AtomicFileWriter interface {
 	Commit() error
 }
 
+// DefaultOpenToChangeTimeout is how long OpenToChange waits for a concurrent
+// change to the same file to complete.
+const DefaultOpenToChangeTimeout = 30 * time.Second
+
+// OpenToChange locks filename and opens it for an atomic change, waiting for
+// up to DefaultOpenToChangeTimeout for concurrent changes to complete.
 func OpenToChange(filename string) (AtomicFileWriter, error) {
+	return OpenToChangeWithOptions(filename, LockOptions{
+		Timeout: DefaultOpenToChangeTimeout,
+		Flock:   true,
+	})
+}
+
+// OpenToChangeWithOptions is like OpenToChange, with opts controlling how the
+// lock on filename is acquired.
+func OpenToChangeWithOptions(filename string, opts LockOptions) (AtomicFileWriter, error) {
 	lockedFile, err := filepath.Abs(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	lock, err := TryLockFile(lockedFile)
+	lock, err := LockFile(context.Background(), lockedFile, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err,
 			"unable to obtain lock on file %v", lockedFile)
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestAtomicFileWriter(t *testing.T) {
@@ -44,10 +45,47 @@ func TestAtomicFileWriter(t *testing.T) {
 		assert.Nil(err)
 		assert.NotNil(state)
 
-		secondState, err := OpenToChange(testFile)
+		secondState, err := OpenToChangeWithOptions(testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+		})
 		assert.NotNil(err)
 		assert.Nil(secondState)
 	})
+	t.Run("OpenToChangeWaitsForConcurrentChange", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		state, err := OpenToChange(testFile)
+		assert.Nil(err)
+		assert.NotNil(state)
+
+		opened := make(chan AtomicFileWriter)
+		go func() {
+			secondState, err := OpenToChange(testFile)
+			assert.Nil(err)
+			opened <- secondState
+		}()
+
+		select {
+		case <-opened:
+			assert.Fail("second change did not wait for the first one")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		state.Write([]byte("Version 1"))
+		assert.Nil(state.Commit())
+
+		secondState := <-opened
+		if assert.NotNil(secondState) {
+			current := secondState.Current()
+			assert.NotNil(current)
+
+			buffer := make([]byte, 20)
+			n, _ := current.Read(buffer)
+			assert.Equal([]byte("Version 1"), buffer[:n])
+
+			secondState.AbortIfPending()
+		}
+	})
 	t.Run("OpenToChangeCanBeUsedSequentially", func(t *testing.T) {
 		assert, testFile := tid.SetupTest(t)
 
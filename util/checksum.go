@@ -0,0 +1,59 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ChecksumMismatchError is returned when the digest of data doesn't match the
+// one expected.
+type ChecksumMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("sha256 checksum mismatch, expected %v but got %v",
+		e.Expected, e.Actual)
+}
+
+type sha256Reader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+// NewSha256Reader returns a reader that computes the SHA-256 digest of data
+// read from r. Once r is exhausted, the reader fails with a
+// ChecksumMismatchError, instead of io.EOF, if the digest doesn't match the
+// expected hex-encoded one.
+//
+// Consumers that only commit data after reading all of it without errors
+// (e.g., stoic.Cache) never commit data that fails verification.
+func NewSha256Reader(r io.Reader, expected string) io.Reader {
+	return &sha256Reader{
+		reader:   r,
+		hash:     sha256.New(),
+		expected: strings.ToLower(strings.TrimSpace(expected)),
+	}
+}
+
+func (sr *sha256Reader) Read(p []byte) (int, error) {
+	n, err := sr.reader.Read(p)
+	sr.hash.Write(p[:n])
+
+	if err == io.EOF {
+		actual := hex.EncodeToString(sr.hash.Sum(nil))
+		if actual != sr.expected {
+			return n, &ChecksumMismatchError{
+				Expected: sr.expected,
+				Actual:   actual,
+			}
+		}
+	}
+	return n, err
+}
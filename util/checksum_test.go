@@ -0,0 +1,42 @@
+package util
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSha256Reader(t *testing.T) {
+	const (
+		content     = "stoic\n"
+		digest      = "9e789db81a013a58475771febdd6c1d48f81a70678a1bb84f4e51784c2e1f487"
+		otherDigest = "08def326e37f685dccfe276018209db4afdfef2d97b022352f63a3d003368504"
+	)
+
+	t.Run("Match", func(t *testing.T) {
+		assert := assert.New(t)
+
+		data, err := ioutil.ReadAll(NewSha256Reader(strings.NewReader(content), digest))
+		assert.Nil(err)
+		assert.Equal(content, string(data))
+	})
+	t.Run("MatchIgnoresCase", func(t *testing.T) {
+		assert := assert.New(t)
+
+		expected := strings.ToUpper(digest)
+		_, err := ioutil.ReadAll(NewSha256Reader(strings.NewReader(content), expected))
+		assert.Nil(err)
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := ioutil.ReadAll(NewSha256Reader(strings.NewReader(content), otherDigest))
+		if assert.IsType(&ChecksumMismatchError{}, err) {
+			mismatch := err.(*ChecksumMismatchError)
+			assert.Equal(otherDigest, mismatch.Expected)
+			assert.Equal(digest, mismatch.Actual)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+	"github.com/pkg/errors"
+)
+
+// RunCredentialHelper runs the command line in helper, with host appended as
+// the last argument, and returns the token it prints.
+func RunCredentialHelper(helper, host string) (string, error) {
+	args, err := shlex.Split(helper)
+	if err != nil || len(args) == 0 {
+		return "", errors.Errorf("invalid credential helper for %v, '%v'", host, helper)
+	}
+
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(args[0], append(args[1:], host)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return "", errors.Wrapf(err, "credential helper for %v failed", host)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.Errorf("credential helper for %v returned no token", host)
+	}
+	return token, nil
+}
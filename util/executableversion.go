@@ -0,0 +1,46 @@
+package util
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// CachedVersion returns the version of executable, as recorded in dir for the
+// current path, size and modification time of the executable. Otherwise, the
+// version is detected with detectVersion, and recorded.
+func CachedVersion(dir, executable string, detectVersion func(string) (string, error)) (string, error) {
+	fi, err := os.Stat(executable)
+	if err != nil {
+		return detectVersion(executable)
+	}
+
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\n%d\n%d",
+		executable, fi.ModTime().UnixNano(), fi.Size())))
+	versionFile := filepath.Join(dir, fmt.Sprintf("%x", key))
+
+	if content, err := ioutil.ReadFile(versionFile); err == nil {
+		if version := strings.TrimSpace(string(content)); version != "" {
+			return version, nil
+		}
+	}
+
+	version, err := detectVersion(executable)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(versionFile, []byte(version+"\n"), 0644)
+	}
+	if err != nil {
+		jww.DEBUG.Printf("failed to record version of %v: %v", executable, err)
+	}
+	return version, nil
+}
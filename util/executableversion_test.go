@@ -0,0 +1,42 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedVersion(t *testing.T) {
+	tid := SetupTestInDir(t)
+	defer tid.Close()
+
+	executable := filepath.Join(tid.TestDir(), "python")
+	assert.Nil(t, ioutil.WriteFile(executable, nil, 0755))
+
+	calls := 0
+	detectVersion := func(string) (string, error) {
+		calls++
+		return "3.9.1", nil
+	}
+
+	dir := filepath.Join(tid.TestDir(), "versions")
+	for i := 0; i < 2; i++ {
+		version, err := CachedVersion(dir, executable, detectVersion)
+		assert.Nil(t, err)
+		assert.Equal(t, "3.9.1", version)
+		assert.Equal(t, 1, calls)
+	}
+
+	// An upgraded executable is detected again
+	later := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(executable, later, later))
+
+	version, err := CachedVersion(dir, executable, detectVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, "3.9.1", version)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,11 @@
+package util
+
+import (
+	"os/exec"
+)
+
+// ExitStatusError is returned when a tool run as a child process fails. Its
+// exit status is to be mirrored by stoic, rather than reported as an error.
+type ExitStatusError struct {
+	*exec.ExitError
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ExitWithStatus exits with the exit status of a child process. If the child
+// was killed by a signal, the current process is killed by the same signal,
+// so that the parent observes the same status.
+func ExitWithStatus(state *os.ProcessState) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		os.Exit(state.ExitCode())
+	}
+
+	if status.Signaled() {
+		sig := status.Signal()
+		signal.Reset(sig)
+		syscall.Kill(os.Getpid(), sig)
+
+		// Not all signals terminate the process, follow the convention of
+		// shells otherwise
+		os.Exit(128 + int(sig))
+	}
+	os.Exit(status.ExitStatus())
+}
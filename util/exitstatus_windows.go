@@ -0,0 +1,10 @@
+package util
+
+import (
+	"os"
+)
+
+// ExitWithStatus exits with the exit status of a child process.
+func ExitWithStatus(state *os.ProcessState) {
+	os.Exit(state.ExitCode())
+}
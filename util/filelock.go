@@ -1,10 +1,27 @@
 package util
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	lockMinDelay = 10 * time.Millisecond
+	lockMaxDelay = time.Second
+
+	// lockSetupGracePeriod is how long a lock file may go without describing
+	// its holder before it's deemed abandoned.
+	lockSetupGracePeriod = 10 * time.Second
 )
 
 type FileLock interface {
@@ -15,6 +32,20 @@ type FileLock interface {
 	Unlock()
 }
 
+// LockOptions controls how LockFile waits for and holds a lock.
+type LockOptions struct {
+	// Timeout limits how long to wait for the lock to be released by another
+	// holder. A zero Timeout waits until the context is done.
+	Timeout time.Duration
+
+	// Flock additionally holds a flock(2) lock on the lock file, on platforms
+	// that support it (Linux). The kernel releases it when the holding process
+	// exits, letting other processes reliably detect locks left behind by
+	// crashed processes, even when the PID of the crashed process has been
+	// reused.
+	Flock bool
+}
+
 type fileLock struct {
 	filename string
 	lock     *os.File
@@ -32,6 +63,60 @@ func TryLockFile(filename string) (FileLock, error) {
 		return nil, err
 	}
 
+	return tryLockFile(filename, false)
+}
+
+// LockFile is a blocking variant of TryLockFile. While the lock is held by
+// someone else, it retries with increasing delays until the lock is acquired,
+// opts.Timeout expires or ctx is done.
+//
+// Locks left behind by processes that are no longer running are detected and
+// broken. A lock is deemed stale if it was created on the current host by a
+// PID that is no longer alive or, when it is held with opts.Flock, if its
+// flock(2) lock has been released.
+func LockFile(ctx context.Context, filename string, opts LockOptions) (FileLock, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	lockFile := filename + ".lock"
+	delay := lockMinDelay
+	for {
+		lock, err := tryLockFile(filename, opts.Flock)
+		if err == nil {
+			return lock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if breakStaleLock(lockFile) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(),
+				"unable to obtain lock on file %v (remove %v if no other "+
+					"process is holding it)", filename, lockFile)
+
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > lockMaxDelay {
+			delay = lockMaxDelay
+		}
+	}
+}
+
+func tryLockFile(filename string, useFlock bool) (FileLock, error) {
 	lockFile := filename + ".lock"
 
 	lock, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
@@ -39,15 +124,32 @@ func TryLockFile(filename string) (FileLock, error) {
 		return nil, err
 	}
 
+	// The flock must be in place before the lock is fully described, so that
+	// others never mistake the lock for a stale one. It may be briefly held by
+	// a process checking whether the lock is stale.
+	useFlock = useFlock && flockSupported
+	if useFlock {
+		if err := flockFile(lock, true); err != nil {
+			os.Remove(lockFile)
+			lock.Close()
+			return nil, err
+		}
+	}
+
 	executable, _ := os.Executable()
 	pid := os.Getpid()
+	host, _ := os.Hostname()
 	timestamp := time.Now()
 
 	fmt.Fprintf(lock,
 		"Cooperative lock on file '%v', created on %v, by %v (PID %v)\n"+
 			"It's safe to delete this file if the process that produced it is "+
-			"no longer running (e.g., the process may have crashed).\n",
-		filename, timestamp, executable, pid)
+			"no longer running (e.g., the process may have crashed).\n"+
+			"\n"+
+			"PID: %v\n"+
+			"Host: %v\n"+
+			"Flock: %v\n",
+		filename, timestamp, executable, pid, pid, host, useFlock)
 
 	return &fileLock{
 		filename: filename,
@@ -69,3 +171,133 @@ func (fl *fileLock) Unlock() {
 	fl.lock.Close()
 	fl.lock = nil
 }
+
+type lockInfo struct {
+	pid   int
+	host  string
+	flock bool
+}
+
+// parseLockInfo extracts information on the holder of a lock from the
+// contents of a lock file. It fails for locks that are still being set up.
+func parseLockInfo(content []byte) (lockInfo, bool) {
+	var info lockInfo
+	var havePID, haveHost, haveFlock bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "PID: "):
+			pid, err := strconv.Atoi(strings.TrimPrefix(line, "PID: "))
+			info.pid, havePID = pid, err == nil
+		case strings.HasPrefix(line, "Host: "):
+			info.host, haveHost = strings.TrimPrefix(line, "Host: "), true
+		case strings.HasPrefix(line, "Flock: "):
+			flock, err := strconv.ParseBool(strings.TrimPrefix(line, "Flock: "))
+			info.flock, haveFlock = flock, err == nil
+		}
+	}
+
+	return info, havePID && haveHost && haveFlock
+}
+
+// isStaleLock reports whether the lock described by content, last modified at
+// modTime, was left behind by a process that is no longer running. Where
+// flock(2) is supported, it must be called while holding the flock(2) lock on
+// the lock file, which tells that its holder released it.
+func isStaleLock(content []byte, modTime time.Time) bool {
+	info, ok := parseLockInfo(content)
+	if !ok {
+		// Locks still being set up are given a grace period
+		return time.Since(modTime) >= lockSetupGracePeriod
+	}
+
+	if info.flock && flockSupported {
+		return true
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host != info.host {
+		// Unable to tell if the process is still alive
+		return false
+	}
+	return !isProcessAlive(info.pid)
+}
+
+// breakStaleLock removes lockFile if it was left behind by a process that is
+// no longer running. It returns true if the lock was removed.
+//
+// Processes breaking a lock are serialized, and the lock is checked again once
+// they're done, so that a lock that was just created in place of a stale one
+// is never removed.
+func breakStaleLock(lockFile string) bool {
+	if flockSupported {
+		return breakStaleLockWithFlock(lockFile)
+	}
+	return breakStaleLockWithGuard(lockFile)
+}
+
+// breakStaleLockWithFlock serializes breaking with a flock(2) lock on the lock
+// file itself. Holders using flock(2) keep it for as long as they hold the
+// lock, so it's only acquired on locks that were released.
+func breakStaleLockWithFlock(lockFile string) bool {
+	file, err := os.Open(lockFile)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if err := flockFile(file, false); err != nil {
+		// Held, or being broken by another process
+		return false
+	}
+
+	// The lock may have been replaced before it was opened
+	fi, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	if current, err := os.Stat(lockFile); err != nil || !os.SameFile(fi, current) {
+		return false
+	}
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil || !isStaleLock(content, fi.ModTime()) {
+		return false
+	}
+	return os.Remove(lockFile) == nil
+}
+
+// breakStaleLockWithGuard serializes breaking with a guard file that is
+// atomically created next to the lock file. A guard left behind by a process
+// that crashed while breaking a lock prevents the lock from being broken again,
+// until the guard is removed.
+func breakStaleLockWithGuard(lockFile string) bool {
+	guardFile := lockFile + ".break"
+	guard, err := os.OpenFile(guardFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		guard.Close()
+		os.Remove(guardFile)
+	}()
+
+	file, err := os.Open(lockFile)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil || !isStaleLock(content, fi.ModTime()) {
+		return false
+	}
+	return os.Remove(lockFile) == nil
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+const flockSupported = true
+
+// flockFile acquires an exclusive flock(2) lock on file. Unless wait is set, it
+// fails right away if the lock is held elsewhere. The lock is held until file
+// is closed, or the process exits.
+func flockFile(file *os.File, wait bool) error {
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	return syscall.Flock(int(file.Fd()), how)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"os"
+)
+
+const flockSupported = false
+
+func flockFile(file *os.File, wait bool) error {
+	panic("flock(2) is not supported on this platform")
+}
@@ -1,9 +1,16 @@
 package util
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestFileLock(t *testing.T) {
@@ -127,3 +134,248 @@ func TestFileLock(t *testing.T) {
 		lock.Unlock()
 	})
 }
+
+func TestLockFile(t *testing.T) {
+	tid := SetupTestInDir(t)
+	defer tid.Close()
+
+	writeLockFile := func(testFile string, pid int, host string, flock bool) {
+		err := ioutil.WriteFile(testFile+".lock", []byte(fmt.Sprintf(
+			"Cooperative lock on file '%v'\n\nPID: %v\nHost: %v\nFlock: %v\n",
+			testFile, pid, host, flock)), 0444)
+		if err != nil {
+			t.Fatalf("unable to write lock file: %v", err)
+		}
+	}
+
+	deadPID := func() int {
+		cmd := exec.Command(os.Args[0], "-test.run=^$")
+		if err := cmd.Run(); err != nil {
+			t.Skipf("unable to run process: %v", err)
+		}
+		return cmd.Process.Pid
+	}
+
+	t.Run("LockFileSucceedsOnUnlockedFile", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{})
+		assert.Nil(err)
+		assert.NotNil(lock)
+
+		absTestFile, err := filepath.Abs(testFile)
+		assert.Nil(err)
+		assert.Equal(absTestFile, lock.Name())
+
+		lock.Unlock()
+
+		_, err = os.Stat(testFile + ".lock")
+		assert.True(os.IsNotExist(err))
+	})
+	t.Run("LockFileWaitsForUnlock", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		lock, err := TryLockFile(testFile)
+		assert.Nil(err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			lock.Unlock()
+		}()
+
+		secondLock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 10 * time.Second,
+		})
+		assert.Nil(err)
+		if assert.NotNil(secondLock) {
+			secondLock.Unlock()
+		}
+	})
+	t.Run("LockFileTimesOut", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		lock, err := TryLockFile(testFile)
+		assert.Nil(err)
+		defer lock.Unlock()
+
+		secondLock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		assert.NotNil(err)
+		assert.Nil(secondLock)
+	})
+	t.Run("LockFileStopsWhenContextIsDone", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		lock, err := TryLockFile(testFile)
+		assert.Nil(err)
+		defer lock.Unlock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		secondLock, err := LockFile(ctx, testFile, LockOptions{})
+		assert.NotNil(err)
+		assert.Nil(secondLock)
+	})
+	t.Run("LockFileBreaksLockOfDeadProcess", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		host, err := os.Hostname()
+		assert.Nil(err)
+		writeLockFile(testFile, deadPID(), host, false)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 10 * time.Second,
+		})
+		assert.Nil(err)
+		if assert.NotNil(lock) {
+			lock.Unlock()
+		}
+	})
+	t.Run("LockFileKeepsLockOfLiveProcess", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		host, err := os.Hostname()
+		assert.Nil(err)
+		writeLockFile(testFile, os.Getpid(), host, false)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		assert.NotNil(err)
+		assert.Nil(lock)
+	})
+	t.Run("LockFileKeepsLockFromOtherHost", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		writeLockFile(testFile, deadPID(), "other-host.invalid", false)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		assert.NotNil(err)
+		assert.Nil(lock)
+	})
+	t.Run("LockFileBreaksAbandonedEmptyLock", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		// Left behind by a process that crashed before describing the lock
+		assert.Nil(ioutil.WriteFile(testFile+".lock", nil, 0444))
+		past := time.Now().Add(-2 * lockSetupGracePeriod)
+		assert.Nil(os.Chtimes(testFile+".lock", past, past))
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 10 * time.Second,
+		})
+		assert.Nil(err)
+		if assert.NotNil(lock) {
+			lock.Unlock()
+		}
+	})
+	t.Run("LockFileKeepsEmptyLockBeingSetUp", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		assert.Nil(ioutil.WriteFile(testFile+".lock", nil, 0444))
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		assert.NotNil(err)
+		assert.Nil(lock)
+	})
+	t.Run("LockFileBreaksStaleLockForOneContender", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		host, err := os.Hostname()
+		assert.Nil(err)
+		pid := deadPID()
+
+		for round := 0; round < 20; round++ {
+			writeLockFile(testFile, pid, host, false)
+
+			var holders, maxHolders int32
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					lock, err := LockFile(context.Background(), testFile, LockOptions{
+						Timeout: 10 * time.Second,
+					})
+					if !assert.Nil(err) {
+						return
+					}
+
+					n := atomic.AddInt32(&holders, 1)
+					for {
+						max := atomic.LoadInt32(&maxHolders)
+						if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+							break
+						}
+					}
+					time.Sleep(time.Millisecond)
+					atomic.AddInt32(&holders, -1)
+
+					lock.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(int32(1), maxHolders, "round %v", round)
+		}
+	})
+	t.Run("BreakStaleLockWithGuardWaitsForGuard", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		host, err := os.Hostname()
+		assert.Nil(err)
+		writeLockFile(testFile, deadPID(), host, false)
+
+		// Another process is breaking the lock
+		guardFile := testFile + ".lock.break"
+		assert.Nil(ioutil.WriteFile(guardFile, nil, 0444))
+		assert.False(breakStaleLockWithGuard(testFile + ".lock"))
+
+		assert.Nil(os.Remove(guardFile))
+		assert.True(breakStaleLockWithGuard(testFile + ".lock"))
+
+		_, err = os.Stat(guardFile)
+		assert.True(os.IsNotExist(err))
+	})
+	t.Run("LockFileWithFlockBreaksReleasedLock", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+		if !flockSupported {
+			t.Skip("flock(2) is not supported on this platform")
+		}
+
+		// Live PID, but flock(2) lock is not held
+		writeLockFile(testFile, os.Getpid(), "other-host.invalid", true)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 10 * time.Second,
+			Flock:   true,
+		})
+		assert.Nil(err)
+		if assert.NotNil(lock) {
+			lock.Unlock()
+		}
+	})
+	t.Run("LockFileWithFlockKeepsHeldLock", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		lock, err := LockFile(context.Background(), testFile, LockOptions{
+			Flock: true,
+		})
+		assert.Nil(err)
+		defer lock.Unlock()
+
+		secondLock, err := LockFile(context.Background(), testFile, LockOptions{
+			Timeout: 50 * time.Millisecond,
+			Flock:   true,
+		})
+		assert.NotNil(err)
+		assert.Nil(secondLock)
+	})
+}
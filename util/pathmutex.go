@@ -0,0 +1,33 @@
+package util
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+var (
+	pathMutexesLock sync.Mutex
+	pathMutexes     = map[string]*sync.Mutex{}
+)
+
+// PathMutex returns a mutex that is shared by all callers in the current
+// process referring to the same filesystem path. It is meant to serialize
+// changes to resources shared between tools, such as git repositories or python
+// environments, when tools are handled concurrently.
+//
+// PathMutex does not protect against changes from other processes.
+func PathMutex(path string) *sync.Mutex {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+
+	pathMutexesLock.Lock()
+	defer pathMutexesLock.Unlock()
+
+	mutex, ok := pathMutexes[path]
+	if !ok {
+		mutex = &sync.Mutex{}
+		pathMutexes[path] = mutex
+	}
+	return mutex
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathMutex(t *testing.T) {
+	tid := SetupTestInDir(t)
+	defer tid.Close()
+
+	t.Run("SamePathSharesMutex", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		absTestFile, err := filepath.Abs(testFile)
+		assert.Nil(err)
+
+		assert.True(PathMutex(testFile) == PathMutex(testFile))
+		assert.True(PathMutex(testFile) == PathMutex(absTestFile))
+	})
+	t.Run("DifferentPathsDoNotShareMutex", func(t *testing.T) {
+		assert, testFile := tid.SetupTest(t)
+
+		assert.False(PathMutex(testFile) == PathMutex(testFile+"-other"))
+	})
+	t.Run("MutexSerializesAccess", func(t *testing.T) {
+		_, testFile := tid.SetupTest(t)
+
+		var wg sync.WaitGroup
+		var active, maxActive int
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				mutex := PathMutex(testFile)
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				time.Sleep(time.Millisecond)
+				active--
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, maxActive)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"syscall"
+)
+
+// isProcessAlive checks whether a process with the given PID is running on
+// the current host.
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,16 @@
+package util
+
+import (
+	"os"
+)
+
+// isProcessAlive checks whether a process with the given PID is running on
+// the current host.
+func isProcessAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	jww "github.com/spf13/jwalterweatherman"
+)
+
+// sharedResourceLockTimeout limits how long to wait for another process to
+// finish setting up a shared resource.
+const sharedResourceLockTimeout = 15 * time.Minute
+
+// LockSharedResource acquires a lock serializing the setup and removal of the
+// resource at path, such as a python virtual environment shared between tools,
+// across processes. The lock is held on a ".lock"-suffixed file next to path.
+func LockSharedResource(path string) (FileLock, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to obtain lock on %v", path)
+	}
+
+	lock, err := LockFile(context.Background(), path, LockOptions{
+		Timeout: sharedResourceLockTimeout,
+		Flock:   true,
+	})
+	return lock, errors.Wrapf(err, "unable to obtain lock on %v", path)
+}
+
+// TryLockSharedResource acquires the lock taken by LockSharedResource on the
+// resource at path, failing right away if another process is holding it.
+func TryLockSharedResource(path string) (FileLock, error) {
+	return LockFile(context.Background(), path, LockOptions{
+		Timeout: lockMinDelay,
+		Flock:   true,
+	})
+}
+
+// TouchSharedResource records that the resource at path was just used,
+// holding off its collection as garbage.
+func TouchSharedResource(path string) {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		jww.DEBUG.Printf("unable to update modification time of %v: %v", path, err)
+	}
+}
+
+// GlobSharedResources returns the shared resources matching pattern, leaving
+// out the files used to lock them.
+func GlobSharedResources(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".lock") && !strings.Contains(match, ".lock.break") {
+			resources = append(resources, match)
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedResources(t *testing.T) {
+	tid := SetupTestInDir(t)
+	defer tid.Close()
+
+	env := filepath.Join(tid.TestDir(), "env", "ab", "cdef")
+
+	t.Run("LockSharedResourceCreatesParent", func(t *testing.T) {
+		assert, _ := tid.SetupTest(t)
+
+		lock, err := LockSharedResource(env)
+		assert.Nil(err)
+		if assert.NotNil(lock) {
+			assert.Nil(os.Mkdir(env, 0755))
+
+			// Lock files are not resources
+			resources, err := GlobSharedResources(filepath.Join(tid.TestDir(), "env", "*", "*"))
+			assert.Nil(err)
+			assert.Equal([]string{env}, resources)
+
+			_, err = TryLockFile(env)
+			assert.NotNil(err)
+
+			lock.Unlock()
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"os"
+)
+
+// SetenvForTest sets or, with an empty value, unsets an environment variable
+// for the duration of a test. It returns a function restoring the original
+// value.
+func SetenvForTest(key, value string) func() {
+	original, wasSet := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+
+	return func() {
+		if wasSet {
+			os.Setenv(key, original)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}